@@ -0,0 +1,143 @@
+package koanf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/knadh/koanf/maps"
+)
+
+// AliasConflict decides how a config map that has both a deprecated
+// key path and the key path it is aliased to is merged. See Alias().
+type AliasConflict int
+
+const (
+	// AliasPreferNew keeps the value of the new key path
+	// and discards the value of the deprecated one.
+	AliasPreferNew AliasConflict = iota
+
+	// AliasPreferOld replaces the value of the new key path
+	// with the value of the deprecated one.
+	AliasPreferOld
+
+	// AliasError fails the merge with an error.
+	AliasError
+)
+
+// Alias registers the key path old as a deprecated alias of the key path
+// new. The values of deprecated key paths in config maps that are merged
+// after, for instance, by Load(), are moved to the new key paths and
+// Conf.OnDeprecated is called to report them. If a config map has both
+// key paths, Conf.AliasConflict decides which value is retained. Aliases
+// are not chained and do not apply to Set().
+func (ko *Koanf) Alias(old, new string) {
+	if ko.aliases == nil {
+		ko.aliases = make(map[string]string)
+	}
+	ko.aliases[ko.foldPath(old)] = ko.foldPath(new)
+}
+
+// deprecation is a deprecated key path found in a config map
+// to report with Conf.OnDeprecated.
+type deprecation struct {
+	old, new, src string
+}
+
+// applyAliases moves the values of deprecated key paths in the config
+// map c from the source src to their new key paths. If sources, the
+// sources of the individual keys in c, is set, the sources of the keys
+// are moved along.
+func (ko *Koanf) applyAliases(c map[string]interface{}, src string, sources map[string]string) error {
+	if len(ko.aliases) == 0 {
+		return nil
+	}
+
+	olds := make([]string, 0, len(ko.aliases))
+	for o := range ko.aliases {
+		olds = append(olds, o)
+	}
+	sort.Strings(olds)
+
+	for _, old := range olds {
+		var (
+			new      = ko.aliases[old]
+			oldParts = maps.SplitKey(old, ko.conf.Delim)
+			newParts = maps.SplitKey(new, ko.conf.Delim)
+		)
+
+		v := maps.Search(c, oldParts)
+		if v == nil {
+			continue
+		}
+
+		// Deprecations are reported once the change is complete.
+		if ko.conf.OnDeprecated != nil {
+			s := src
+			if sources != nil {
+				s = ko.pathSource(sources, old)
+			}
+			ko.deprecated = append(ko.deprecated, deprecation{old: old, new: new, src: s})
+		}
+
+		if maps.Search(c, newParts) != nil {
+			switch ko.conf.AliasConflict {
+			case AliasError:
+				return fmt.Errorf("deprecated key '%s' conflicts with '%s'", old, new)
+			case AliasPreferNew:
+				maps.Delete(c, oldParts)
+				ko.moveSources(sources, old, "")
+				continue
+			}
+		}
+
+		maps.Delete(c, oldParts)
+		if err := setPath(c, newParts, v, false); err != nil {
+			return err
+		}
+		ko.moveSources(sources, new, "")
+		ko.moveSources(sources, old, new)
+	}
+	return nil
+}
+
+// pathSource returns the source of the flattened key path, or of the
+// first of the flattened key paths under it, in sources.
+func (ko *Koanf) pathSource(sources map[string]string, path string) string {
+	if s, ok := sources[path]; ok {
+		return s
+	}
+
+	var keys []string
+	for k := range sources {
+		if strings.HasPrefix(k, path+ko.conf.Delim) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return ""
+	}
+	sort.Strings(keys)
+	return sources[keys[0]]
+}
+
+// moveSources moves the sources of the flattened key path old, and of
+// the key paths under it, in sources to the key path new. If new is
+// empty, the sources are deleted.
+func (ko *Koanf) moveSources(sources map[string]string, old, new string) {
+	for k, s := range sources {
+		var rest string
+		switch {
+		case k == old:
+		case strings.HasPrefix(k, old+ko.conf.Delim):
+			rest = k[len(old):]
+		default:
+			continue
+		}
+
+		delete(sources, k)
+		if new != "" {
+			sources[new+rest] = s
+		}
+	}
+}
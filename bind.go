@@ -0,0 +1,157 @@
+package koanf
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// BindConf represents configuration options used by Bind().
+type BindConf struct {
+	// UnmarshalConf is used to unmarshal the config into the struct.
+	UnmarshalConf UnmarshalConf
+
+	// OnError is called with the error if the config can't be
+	// unmarshalled after a change, in which case the last good
+	// struct is retained.
+	OnError func(err error)
+
+	// OnUpdate is called with the new struct after it's published.
+	OnUpdate func(v interface{})
+}
+
+// Binding keeps a struct in sync with the config of a Koanf instance.
+// See Bind().
+type Binding struct {
+	ko   *Koanf
+	path string
+	typ  reflect.Type
+	conf BindConf
+
+	// val holds the pointer to the last good struct and
+	// err holds a bindErr with the last unmarshal error.
+	val atomic.Value
+	err atomic.Value
+
+	// mu serializes publishing and version is the version of the
+	// config that was last unmarshalled. See Koanf.version.
+	mu      sync.Mutex
+	version uint64
+}
+
+// bindErr wraps errors as atomic.Value can't store nil
+// or values of different concrete types.
+type bindErr struct {
+	err error
+}
+
+// Bind unmarshals the config at the given key path into o, a pointer to
+// a struct, and returns a Binding that unmarshals the config into a new
+// struct of the same type after every change to the config, for instance,
+// by Load(), Reload(), Set() or Rollback(). The new struct is published
+// atomically and can be read with Binding.Load() from any goroutine.
+// If the config can't be unmarshalled after a change, BindConf.OnError is
+// called and the last good struct is retained. o itself is not changed
+// after Bind() returns.
+func (ko *Koanf) Bind(path string, o interface{}, c BindConf) (*Binding, error) {
+	t := reflect.TypeOf(o)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return nil, errors.New("bind target should be a pointer")
+	}
+
+	b := &Binding{
+		ko:   ko,
+		path: path,
+		typ:  t.Elem(),
+		conf: c,
+	}
+	b.err.Store(bindErr{})
+
+	// The binding is added along with taking the snapshot that o is
+	// unmarshalled from so that no change made meanwhile is missed.
+	ko.mu.Lock()
+	s := ko.snapshot()
+	b.version = ko.version
+	ko.bindings = append(ko.bindings, b)
+	ko.mu.Unlock()
+
+	if err := s.UnmarshalWithConf(path, o, c.UnmarshalConf); err != nil {
+		b.Close()
+		return nil, err
+	}
+
+	// A change made meanwhile may have published a newer struct.
+	b.mu.Lock()
+	if b.val.Load() == nil {
+		b.val.Store(o)
+	}
+	b.mu.Unlock()
+	return b, nil
+}
+
+// Load returns the pointer to the last good struct, for
+// instance, `b.Load().(*Config)`.
+func (b *Binding) Load() interface{} {
+	return b.val.Load()
+}
+
+// Err returns the error of the last unmarshal of the config
+// after a change, or nil if it succeeded.
+func (b *Binding) Err() error {
+	return b.err.Load().(bindErr).err
+}
+
+// Close stops keeping the struct in sync with the config.
+func (b *Binding) Close() {
+	b.ko.mu.Lock()
+	defer b.ko.mu.Unlock()
+
+	out := make([]*Binding, 0, len(b.ko.bindings))
+	for _, o := range b.ko.bindings {
+		if o != b {
+			out = append(out, o)
+		}
+	}
+	b.ko.bindings = out
+}
+
+// update unmarshals the snapshot s of version v of the config into a
+// new struct and publishes it unless a newer version is published.
+func (b *Binding) update(s *Koanf, v uint64) {
+	o := reflect.New(b.typ).Interface()
+
+	// The target struct is set in a custom DecoderConfig.
+	c := b.conf.UnmarshalConf
+	if c.DecoderConfig != nil {
+		dc := *c.DecoderConfig
+		dc.Result = o
+		c.DecoderConfig = &dc
+	}
+
+	err := s.UnmarshalWithConf(b.path, o, c)
+
+	b.mu.Lock()
+	if v <= b.version {
+		b.mu.Unlock()
+		return
+	}
+	b.version = v
+	if err != nil {
+		b.err.Store(bindErr{err: err})
+	} else {
+		b.val.Store(o)
+		b.err.Store(bindErr{})
+	}
+	b.mu.Unlock()
+
+	if err != nil {
+		if b.conf.OnError != nil {
+			b.conf.OnError(err)
+		}
+		return
+	}
+	if b.conf.OnUpdate != nil {
+		b.conf.OnUpdate(o)
+	}
+}
@@ -0,0 +1,160 @@
+package koanf
+
+import (
+	"fmt"
+	"os"
+)
+
+// Locator is an optional interface that a Provider can implement to
+// report the location of its source, for instance, a file path, in the
+// errors returned by Load().
+type Locator interface {
+	Location() string
+}
+
+// ProviderError is returned by Load() when a Provider fails to read.
+type ProviderError struct {
+	// Provider is the name of the provider without the location
+	// of its source, which is in Path, for instance, `*file.File`.
+	Provider string
+
+	// Path is the location of the provider's source if
+	// it implements Locator, for instance, a file path.
+	Path string
+
+	Err error
+}
+
+// ParseError is returned by Load() when a Parser fails to parse
+// the bytes read from a Provider.
+type ParseError struct {
+	// Provider is the name of the provider without the location
+	// of its source, which is in Path, for instance, `*file.File`.
+	Provider string
+
+	// Path is the location of the provider's source if
+	// it implements Locator, for instance, a file path.
+	Path string
+
+	// Line and Column are the position of the error in the document,
+	// starting at 1, if the Parser's error reports it with a
+	// `Position() (line, column int)` method, as the errors of the
+	// bundled parsers do. Otherwise, they are 0.
+	Line   int
+	Column int
+
+	Err error
+}
+
+// MergeError is returned by Load() when the config map read from a
+// Provider can't be merged, for instance, if it has keys that differ only
+// in case with Conf.CaseInsensitive, or conflicting deprecated keys with
+// AliasError.
+type MergeError struct {
+	// Provider is the name of the provider without the location
+	// of its source, which is in Path, for instance, `*file.File`.
+	Provider string
+
+	// Path is the location of the provider's source if
+	// it implements Locator, for instance, a file path.
+	Path string
+
+	Err error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("error reading %s: %v", describe(e.Provider, e.Path), e.Err)
+}
+
+// Unwrap returns the error from the Provider.
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ParseError) Error() string {
+	pos := ""
+	switch {
+	case e.Column > 0:
+		pos = fmt.Sprintf(" at line %d, column %d", e.Line, e.Column)
+	case e.Line > 0:
+		pos = fmt.Sprintf(" at line %d", e.Line)
+	}
+	return fmt.Sprintf("error parsing %s%s: %v", describe(e.Provider, e.Path), pos, e.Err)
+}
+
+// Unwrap returns the error from the Parser.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (e *MergeError) Error() string {
+	return fmt.Sprintf("error merging %s: %v", describe(e.Provider, e.Path), e.Err)
+}
+
+// Unwrap returns the error from merging.
+func (e *MergeError) Unwrap() error {
+	return e.Err
+}
+
+// describe describes a provider by its name and
+// the location of its source, if there's one.
+func describe(provider, path string) string {
+	if path == "" {
+		return provider
+	}
+	return fmt.Sprintf("%s '%s'", provider, path)
+}
+
+// providerError returns a ProviderError with the given cause for p.
+func providerError(p Provider, err error) error {
+	return &ProviderError{Provider: providerType(p), Path: location(p), Err: err}
+}
+
+// parseError returns a ParseError with the given cause for p
+// and the position of the error if the cause reports it.
+func parseError(p Provider, err error) error {
+	e := &ParseError{Provider: providerType(p), Path: location(p), Err: err}
+	if pe, ok := err.(interface {
+		Position() (line, column int)
+	}); ok {
+		e.Line, e.Column = pe.Position()
+	}
+	return e
+}
+
+// mergeError returns a MergeError with the given cause for p.
+func mergeError(p Provider, err error) error {
+	return &MergeError{Provider: providerType(p), Path: location(p), Err: err}
+}
+
+// location returns the location of the provider's source
+// if it implements Locator.
+func location(p Provider) string {
+	if l, ok := p.(Locator); ok {
+		return l.Location()
+	}
+	return ""
+}
+
+// isNotExist returns true if the error is a ProviderError
+// for a source that doesn't exist.
+func isNotExist(err error) bool {
+	e, ok := err.(*ProviderError)
+	return ok && notExist(e.Err)
+}
+
+// notExist returns true if os.IsNotExist() is true for the error
+// or any of the errors it wraps with an `Unwrap() error` method.
+func notExist(err error) bool {
+	for err != nil {
+		if os.IsNotExist(err) {
+			return true
+		}
+		u, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = u.Unwrap()
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package koanf
+
+// Freeze makes the instance read-only so that nothing changes the config
+// after initialisation. Methods that change the config, such as Load(),
+// Merge(), Set() and Delete(), return an error after it's called.
+// Instances returned by Cut() and Copy() are frozen too and share the
+// config with the instance instead of copying it.
+func (ko *Koanf) Freeze() {
+	ko.readOnly = true
+}
+
+// Frozen returns true if the instance is read-only, that
+// is, if it is frozen or is a snapshot (see Snapshot()).
+func (ko *Koanf) Frozen() bool {
+	return ko.readOnly
+}
@@ -5,6 +5,24 @@ import (
 	"time"
 )
 
+// Pseudo layouts accepted by TimeLayouts for numeric UNIX timestamps.
+// LayoutUnixAuto treats values with a magnitude of 1e11 or more, which
+// are past the year 5000 in seconds, as milliseconds, and smaller values
+// as seconds.
+const (
+	LayoutUnix      = "unix"
+	LayoutUnixMilli = "unixmilli"
+	LayoutUnixAuto  = "unixauto"
+)
+
+// DefaultTimeLayouts is the list of layouts TimeLayouts tries when
+// none are given.
+var DefaultTimeLayouts = []string{time.RFC3339, "2006-01-02", LayoutUnixAuto}
+
+// unixMilliMin is the magnitude from which LayoutUnixAuto
+// treats timestamps as milliseconds.
+const unixMilliMin = 1e11
+
 // Int64 returns the int64 value of a given key path or 0 if the path
 // does not exist or if the value is not a valid int64.
 func (ko *Koanf) Int64(path string) int64 {
@@ -278,15 +296,15 @@ func (ko *Koanf) MustFloat64Map(path string) map[string]float64 {
 }
 
 // Duration returns the time.Duration value of a given key path assuming
-// that the key contains a valid numeric value.
+// that the key contains a valid numeric value or a string representation
+// like "3s". Numeric values are multiplied by Conf.DurationUnit
+// (nanoseconds by default).
 func (ko *Koanf) Duration(path string) time.Duration {
-	// Look for a parsable string representation first.
-	if v := ko.Int64(path); v > 0 {
-		return time.Duration(v)
+	if v := ko.Get(path); v != nil {
+		d, _ := toDuration(v, ko.conf.DurationUnit)
+		return d
 	}
-
-	v, _ := time.ParseDuration(ko.String(path))
-	return v
+	return 0
 }
 
 // MustDuration returns the time.Duration value of a given key path or panics
@@ -299,6 +317,83 @@ func (ko *Koanf) MustDuration(path string) time.Duration {
 	return val
 }
 
+// Durations returns the []time.Duration slice value of a given key path or an
+// empty []time.Duration slice if the path does not exist or if the value
+// is not a valid duration slice.
+func (ko *Koanf) Durations(path string) []time.Duration {
+	o := ko.Get(path)
+	if o == nil {
+		return []time.Duration{}
+	}
+
+	var out []time.Duration
+	switch v := o.(type) {
+	case []interface{}:
+		out = make([]time.Duration, 0, len(v))
+		for _, vi := range v {
+			d, err := toDuration(vi, ko.conf.DurationUnit)
+
+			// On error, return as it's not a valid
+			// duration slice.
+			if err != nil {
+				return []time.Duration{}
+			}
+			out = append(out, d)
+		}
+		return out
+	}
+
+	return []time.Duration{}
+}
+
+// MustDurations returns the []time.Duration slice value of a given key path or panics
+// if the value is not set or set to default value.
+func (ko *Koanf) MustDurations(path string) []time.Duration {
+	val := ko.Durations(path)
+	if len(val) == 0 {
+		panic(fmt.Sprintf("invalid value: %s=%v", path, val))
+	}
+	return val
+}
+
+// DurationMap returns the map[string]time.Duration value of a given key path
+// or an empty map[string]time.Duration if the path does not exist or if the
+// value is not a valid duration map.
+func (ko *Koanf) DurationMap(path string) map[string]time.Duration {
+	var (
+		out = map[string]time.Duration{}
+		o   = ko.Get(path)
+	)
+	if o == nil {
+		return out
+	}
+
+	mp, ok := o.(map[string]interface{})
+	if !ok {
+		return out
+	}
+
+	out = make(map[string]time.Duration, len(mp))
+	for k, v := range mp {
+		d, err := toDuration(v, ko.conf.DurationUnit)
+		if err != nil {
+			return map[string]time.Duration{}
+		}
+		out[k] = d
+	}
+	return out
+}
+
+// MustDurationMap returns the map[string]time.Duration value of a given key path
+// or panics if its not set or set to default value.
+func (ko *Koanf) MustDurationMap(path string) map[string]time.Duration {
+	val := ko.DurationMap(path)
+	if len(val) == 0 {
+		panic(fmt.Sprintf("invalid value: %s=%v", path, val))
+	}
+	return val
+}
+
 // Time attempts to parse the value of a given key path and return time.Time
 // representation. If the value is numeric, it is treated as a UNIX timestamp
 // and if it's string, a parse is attempted with the given layout.
@@ -331,6 +426,41 @@ func (ko *Koanf) MustTime(path, layout string) time.Time {
 	return val
 }
 
+// TimeLayouts attempts to parse the value of a given key path with each of
+// the given layouts in order and returns the first successfully parsed
+// time.Time. In addition to time.Parse layouts, the pseudo layouts
+// LayoutUnix and LayoutUnixMilli treat numeric values as UNIX timestamps
+// in seconds and milliseconds respectively, and LayoutUnixAuto in either
+// depending on their magnitude. If no layouts are given, DefaultTimeLayouts
+// are used.
+func (ko *Koanf) TimeLayouts(path string, layouts ...string) time.Time {
+	v := ko.Get(path)
+	if v == nil {
+		return time.Time{}
+	}
+
+	if len(layouts) == 0 {
+		layouts = DefaultTimeLayouts
+	}
+
+	for _, l := range layouts {
+		if t, err := toTime(v, l); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// MustTimeLayouts is like TimeLayouts but panics if the value of the
+// given key path cannot be parsed with any of the layouts.
+func (ko *Koanf) MustTimeLayouts(path string, layouts ...string) time.Time {
+	val := ko.TimeLayouts(path, layouts...)
+	if val.IsZero() {
+		panic(fmt.Sprintf("invalid value: %s=%v", path, val))
+	}
+	return val
+}
+
 // String returns the string value of a given key path or "" if the path
 // does not exist or if the value is not a valid string.
 func (ko *Koanf) String(path string) string {
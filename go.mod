@@ -6,11 +6,13 @@ require (
 	github.com/fatih/structs v1.1.0
 	github.com/fsnotify/fsnotify v1.4.9
 	github.com/hashicorp/hcl v1.0.0
+	github.com/jmespath/go-jmespath v0.4.0
 	github.com/mitchellh/mapstructure v1.2.2
 	github.com/pelletier/go-toml v1.7.0
 	github.com/rhnvrm/simples3 v0.5.0
 	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.3.0
+	github.com/xeipuuv/gojsonschema v1.2.0
 	golang.org/x/sys v0.0.0-20200331124033-c3d80250170d // indirect
 	gopkg.in/yaml.v2 v2.2.8
 )
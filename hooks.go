@@ -0,0 +1,116 @@
+package koanf
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// DefaultDecodeHooks returns the decode hooks Unmarshal() uses by default.
+// Hooks in UnmarshalConf.DecodeHooks are composed after these and are
+// applied in order, each receiving the output of the previous one.
+func DefaultDecodeHooks() []mapstructure.DecodeHookFunc {
+	return []mapstructure.DecodeHookFunc{
+		mapstructure.StringToTimeDurationHookFunc(),
+	}
+}
+
+// StringToSliceHook returns a decode hook that splits strings by sep
+// into []string, for instance, `hosts: "a,b,c"` into a []string field.
+func StringToSliceHook(sep string) mapstructure.DecodeHookFunc {
+	return mapstructure.StringToSliceHookFunc(sep)
+}
+
+// StringToIPHook returns a decode hook that parses strings into net.IP.
+func StringToIPHook() mapstructure.DecodeHookFunc {
+	return mapstructure.StringToIPHookFunc()
+}
+
+// StringToIPNetHook returns a decode hook that parses CIDR strings
+// into net.IPNet.
+func StringToIPNetHook() mapstructure.DecodeHookFunc {
+	return mapstructure.StringToIPNetHookFunc()
+}
+
+// StringToTimeHook returns a decode hook that parses values into time.Time
+// with the first of the given layouts that succeeds. Like TimeLayouts(),
+// it accepts the pseudo layouts LayoutUnix, LayoutUnixMilli and
+// LayoutUnixAuto and uses
+// DefaultTimeLayouts if no layouts are given.
+func StringToTimeHook(layouts ...string) mapstructure.DecodeHookFunc {
+	if len(layouts) == 0 {
+		layouts = DefaultTimeLayouts
+	}
+
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t != reflect.TypeOf(time.Time{}) || f == t {
+			return data, nil
+		}
+
+		for _, l := range layouts {
+			if tm, err := toTime(data, l); err == nil {
+				return tm, nil
+			}
+		}
+		return nil, fmt.Errorf("unable to parse time '%v' with layouts %v", data, layouts)
+	}
+}
+
+// StringToRegexpHook returns a decode hook that compiles strings
+// into regexp.Regexp and *regexp.Regexp.
+func StringToRegexpHook() mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String {
+			return data, nil
+		}
+
+		switch t {
+		case reflect.TypeOf(regexp.Regexp{}):
+			re, err := regexp.Compile(data.(string))
+			if err != nil {
+				return nil, err
+			}
+			return *re, nil
+		case reflect.TypeOf(&regexp.Regexp{}):
+			return regexp.Compile(data.(string))
+		}
+		return data, nil
+	}
+}
+
+// TextUnmarshalerHook returns a decode hook that decodes strings into
+// types that implement encoding.TextUnmarshaler, for instance, net.IP
+// and big.Int, or custom enum types. As hooks are applied in order,
+// more specific hooks such as StringToTimeHook() should precede it,
+// and StringToSliceHook() should follow it.
+func TextUnmarshalerHook() mapstructure.DecodeHookFunc {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String {
+			return data, nil
+		}
+
+		// The target is a pointer to a TextUnmarshaler.
+		ptr := t.Kind() == reflect.Ptr
+		if ptr {
+			t = t.Elem()
+		}
+
+		v := reflect.New(t)
+		u, ok := v.Interface().(encoding.TextUnmarshaler)
+		if !ok {
+			return data, nil
+		}
+		if err := u.UnmarshalText([]byte(data.(string))); err != nil {
+			return nil, err
+		}
+
+		if ptr {
+			return v.Interface(), nil
+		}
+		return v.Elem().Interface(), nil
+	}
+}
@@ -1,5 +1,7 @@
 package koanf
 
+import "context"
+
 // Provider represents a configuration provider. Providers can
 // read configuration from a source (file, HTTP etc.)
 type Provider interface {
@@ -18,6 +20,20 @@ type Provider interface {
 	Watch(func(event interface{}, err error)) error
 }
 
+// ContextProvider is an optional interface that a Provider can implement
+// to stop reading when a context is cancelled or its deadline passes.
+// LoadContext() uses these methods instead of ReadBytes() and Read()
+// if the Provider implements them.
+type ContextProvider interface {
+	// ReadBytesContext is like ReadBytes but returns ctx.Err()
+	// if the context is done before the read completes.
+	ReadBytesContext(ctx context.Context) ([]byte, error)
+
+	// ReadContext is like Read but returns ctx.Err()
+	// if the context is done before the read completes.
+	ReadContext(ctx context.Context) (map[string]interface{}, error)
+}
+
 // Parser represents a configuration format parser.
 type Parser interface {
 	Unmarshal([]byte) (map[string]interface{}, error)
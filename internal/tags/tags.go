@@ -0,0 +1,87 @@
+// Package tags maps the fields of structs to the keys of conf maps based
+// on struct field tags, as mapstructure does when config is unmarshalled.
+// It is shared by koanf and its subpackages, such as structdoc, so that
+// they all follow the same rules.
+package tags
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Field is a field in a struct with the name of the key it maps to
+// in a conf map based on the struct field tag.
+type Field struct {
+	reflect.StructField
+
+	// Key is the name of the key in the conf map.
+	Key string
+
+	// Remain is true for fields with the `remain` tag option
+	// that collect all unmatched keys.
+	Remain bool
+}
+
+// Fields returns the fields of the struct type t based on the given
+// struct field tag. The fields of embedded structs with the `squash` tag
+// option are returned as fields of t. Fields tagged `-` are skipped.
+func Fields(t reflect.Type, tag string) []Field {
+	var out []Field
+	for i := 0; i < t.NumField(); i++ {
+		var (
+			f              = t.Field(i)
+			parts          = strings.Split(f.Tag.Get(tag), ",")
+			squash, remain bool
+		)
+		if parts[0] == "-" {
+			continue
+		}
+		for _, o := range parts[1:] {
+			switch o {
+			case "squash":
+				squash = true
+			case "remain":
+				remain = true
+			}
+		}
+
+		if squash && f.Type.Kind() == reflect.Struct {
+			out = append(out, Fields(f.Type, tag)...)
+			continue
+		}
+
+		key := parts[0]
+		if key == "" {
+			key = f.Name
+		}
+		out = append(out, Field{StructField: f, Key: key, Remain: remain})
+	}
+	return out
+}
+
+// Default returns the default value from a `default` tag for a field
+// of type t. Values for slices are split by commas.
+func Default(d string, t reflect.Type) interface{} {
+	switch Deref(t).Kind() {
+	case reflect.Slice, reflect.Array:
+		if d == "" {
+			return []interface{}{}
+		}
+
+		parts := strings.Split(d, ",")
+		out := make([]interface{}, len(parts))
+		for i, p := range parts {
+			out[i] = strings.TrimSpace(p)
+		}
+		return out
+	}
+	return d
+}
+
+// Deref returns the type that t points to if t is a pointer.
+func Deref(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,321 @@
+package koanf
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/knadh/koanf/maps"
+)
+
+// Resolver resolves the key in a reference like `${env:VAR}` where `env`
+// is the name the Resolver is registered with in InterpolateConf and `VAR`
+// is the key. An error indicates that the key could not be resolved.
+type Resolver func(key string) (string, error)
+
+// InterpolateConf represents configuration options used by
+// Interpolate() to resolve references in config values.
+type InterpolateConf struct {
+	// Resolvers is a map of resolver names and Resolvers. A reference
+	// `${name:key}` where `name` is in the map is resolved with the Resolver
+	// instead of being looked up as a key path in the conf map. For instance,
+	// {"env": EnvResolver, "file": FileResolver}. Resolvers are called
+	// during the changes to the config that interpolate it, while the
+	// instance is locked, and must not call the methods of the instance.
+	Resolvers map[string]Resolver
+}
+
+// EnvResolver is a Resolver that resolves keys as environment variables.
+func EnvResolver(key string) (string, error) {
+	v, ok := os.LookupEnv(key)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", key)
+	}
+	return v, nil
+}
+
+// FileResolver is a Resolver that resolves keys as file paths and returns
+// the contents of the files with trailing newlines trimmed.
+func FileResolver(key string) (string, error) {
+	b, err := ioutil.ReadFile(key)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// cycleError is returned when references form a cycle. Unlike unresolved
+// references, cycles are not substituted with default values.
+type cycleError struct {
+	path []string
+}
+
+func (e cycleError) Error() string {
+	return fmt.Sprintf("cyclic reference: %s", strings.Join(e.path, " -> "))
+}
+
+// interpolator resolves references in config values.
+type interpolator struct {
+	ko       *Koanf
+	conf     InterpolateConf
+	resolved map[string]interface{}
+	stack    []string
+}
+
+// Interpolate resolves references to other key paths in config values.
+// For instance, `url: "postgres://${db.host}:${db.port}/app"`. It should
+// be called after all providers are loaded. A value that is entirely a
+// single reference, for instance, `port: ${db.port}`, takes the type of
+// the referenced value.
+//
+// `${key:-default}` substitutes default if the key path does not exist
+// and `$${` escapes a literal `${`. References that cannot be resolved and
+// cyclic references are returned as errors, in which case the conf map
+// is not modified. To customize, use InterpolateWithConf().
+//
+// Once Interpolate() succeeds, interpolation is applied again after every
+// change to the config, for instance, by Load(), Set() or Unload(), so that
+// changes to referenced values are reflected. Changes that introduce
+// references that can't be resolved fail and leave the config unchanged.
+func (ko *Koanf) Interpolate() error {
+	return ko.InterpolateWithConf(InterpolateConf{})
+}
+
+// InterpolateWithConf is like Interpolate but takes configuration
+// params in InterpolateConf, for instance, to register Resolvers
+// for references such as `${env:VAR}` and `${file:/path}`.
+func (ko *Koanf) InterpolateWithConf(c InterpolateConf) error {
+	// The config is recomputed from the layers, which keep the raw values,
+	// so that interpolating again doesn't see substituted values, such as
+	// a `${` unescaped from `$${`, as references.
+	return ko.mutate(func() error {
+		prev := ko.interp
+		ko.interp = &c
+		if err := ko.recompute(); err != nil {
+			ko.interp = prev
+			return err
+		}
+		return nil
+	})
+}
+
+// interpolate resolves references in the values of the effective config.
+func (ko *Koanf) interpolate(c InterpolateConf) error {
+	in := &interpolator{
+		ko:       ko,
+		conf:     c,
+		resolved: make(map[string]interface{}),
+	}
+
+	var (
+		out  = make(map[string]interface{})
+		errs []string
+	)
+	for _, k := range ko.Keys() {
+		if !hasRef(ko.confMapFlat[k]) {
+			continue
+		}
+
+		v, err := in.resolveKey(k)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", k, err))
+			continue
+		}
+		out[k] = v
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("error interpolating config: %s", strings.Join(errs, "; "))
+	}
+
+	ko.own()
+	for k, v := range out {
+		if err := setPath(ko.confMap, maps.SplitKey(k, ko.conf.Delim), v, ko.conf.IndexSlices); err != nil {
+			return err
+		}
+	}
+	ko.flatten()
+	return nil
+}
+
+// resolveKey returns the value of the given key path with all
+// references in it resolved.
+func (in *interpolator) resolveKey(path string) (interface{}, error) {
+	path = in.ko.foldPath(path)
+	if v, ok := in.resolved[path]; ok {
+		return v, nil
+	}
+
+	for _, p := range in.stack {
+		if p == path {
+			return nil, cycleError{path: append(append([]string{}, in.stack...), path)}
+		}
+	}
+
+	if !in.ko.Exists(path) {
+		return nil, fmt.Errorf("unresolved reference '%s'", path)
+	}
+
+	in.stack = append(in.stack, path)
+	v, err := in.resolveValue(in.ko.Get(path))
+	in.stack = in.stack[:len(in.stack)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	in.resolved[path] = v
+	return v, nil
+}
+
+// resolveValue resolves references in strings, and recursively,
+// in slices and maps.
+func (in *interpolator) resolveValue(v interface{}) (interface{}, error) {
+	switch c := v.(type) {
+	case string:
+		return in.resolveString(c)
+	case []interface{}:
+		out := make([]interface{}, len(c))
+		for i, item := range c {
+			r, err := in.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = r
+		}
+		return out, nil
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(c))
+		for k, item := range c {
+			r, err := in.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = r
+		}
+		return out, nil
+	}
+	return v, nil
+}
+
+// resolveString substitutes all references in the given string.
+func (in *interpolator) resolveString(s string) (interface{}, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		// Escaped reference.
+		if strings.HasPrefix(s[i:], "$${") {
+			b.WriteString("${")
+			i += 3
+			continue
+		}
+
+		if !strings.HasPrefix(s[i:], "${") {
+			b.WriteByte(s[i])
+			i++
+			continue
+		}
+
+		// Unterminated references are left as they are.
+		end := closingBrace(s, i+2)
+		if end < 0 {
+			b.WriteString(s[i:])
+			break
+		}
+
+		v, err := in.resolveRef(s[i+2 : end])
+		if err != nil {
+			return nil, err
+		}
+
+		// The whole string is a single reference. Retain the
+		// type of the referenced value.
+		if i == 0 && end == len(s)-1 {
+			return v, nil
+		}
+
+		b.WriteString(fmt.Sprintf("%v", v))
+		i = end + 1
+	}
+	return b.String(), nil
+}
+
+// resolveRef resolves a single reference (the string within `${}`) with
+// an optional `:-default` using a Resolver or the conf map.
+func (in *interpolator) resolveRef(ref string) (interface{}, error) {
+	var (
+		key    = ref
+		def    string
+		hasDef bool
+	)
+	if i := strings.Index(ref, ":-"); i >= 0 {
+		key, def, hasDef = ref[:i], ref[i+2:], true
+	}
+
+	var (
+		v   interface{}
+		err error
+	)
+	if r, k, ok := in.resolver(key); ok {
+		v, err = r(k)
+	} else {
+		v, err = in.resolveKey(key)
+	}
+	if err == nil {
+		return v, nil
+	}
+
+	// Cycles are errors irrespective of defaults.
+	if _, ok := err.(cycleError); ok || !hasDef {
+		return nil, err
+	}
+	return in.resolveString(def)
+}
+
+// resolver returns the Resolver and the key for references
+// of the form `name:key` if name is a registered Resolver.
+func (in *interpolator) resolver(ref string) (Resolver, string, bool) {
+	i := strings.Index(ref, ":")
+	if i < 0 {
+		return nil, "", false
+	}
+
+	r, ok := in.conf.Resolvers[ref[:i]]
+	if !ok {
+		return nil, "", false
+	}
+	return r, ref[i+1:], true
+}
+
+// closingBrace returns the index of the } that closes a reference
+// starting at the given position, accounting for nested references,
+// or -1 if there's none.
+func closingBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// hasRef returns true if the given value is a string, or a slice with
+// strings, that may contain references.
+func hasRef(v interface{}) bool {
+	switch c := v.(type) {
+	case string:
+		return strings.Contains(c, "${")
+	case []interface{}:
+		for _, item := range c {
+			if hasRef(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -2,12 +2,18 @@ package koanf
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	gopath "path"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/jmespath/go-jmespath"
 	"github.com/knadh/koanf/maps"
 	"github.com/mitchellh/mapstructure"
 )
@@ -17,7 +23,104 @@ type Koanf struct {
 	confMap     map[string]interface{}
 	confMapFlat map[string]interface{}
 	keyMap      KeyMap
-	delim       string
+	conf        Conf
+
+	// sources is a map of flattened key paths and the names of the
+	// providers that last set them.
+	sources map[string]string
+
+	// sensitive is the list of key path patterns, split into
+	// parts, whose values are redacted. See MarkSensitive().
+	sensitive [][]string
+
+	// aliases is a map of deprecated key paths and the
+	// key paths they are moved to on merge. See Alias().
+	aliases map[string]string
+
+	// layers are the config maps that are merged, in the order of
+	// their priority, into confMap. See Layers().
+	layers []*layer
+
+	// names counts the numbered names that uniqueName() has given
+	// for each name to number the next one without a search.
+	names map[string]int
+
+	// sets are the values set with Set(), in order, that
+	// are applied over the layers.
+	sets []setOp
+
+	// interp is the InterpolateConf of the last Interpolate(). If it's
+	// set, interpolation is applied again after every change.
+	interp *InterpolateConf
+
+	// history is the config before each of the last
+	// Conf.History changes. See Rollback().
+	history []state
+
+	// shared indicates that confMap and sources are shared with
+	// snapshots or the history and have to be copied before they
+	// are changed in place.
+	shared bool
+
+	// readOnly makes the methods that change the config return errors.
+	readOnly bool
+
+	// mu serializes changes to the config with taking snapshots of
+	// it so that Snapshot() can be called while the config changes.
+	mu sync.Mutex
+
+	// deprecated are the deprecated key paths found during a change
+	// that are reported with Conf.OnDeprecated after it.
+	deprecated []deprecation
+
+	// bindings are updated after every change to the config. See Bind().
+	bindings []*Binding
+
+	// version counts the changes to the config so that bindings
+	// don't publish a struct older than the one they have.
+	version uint64
+}
+
+// Conf is the Koanf configuration.
+type Conf struct {
+	// Delim is the delimiter to use
+	// when specifying config key paths, for instance a . for `parent.child.key`
+	// or a / for `parent/child/key`.
+	Delim string
+
+	// DurationUnit is the unit bare numeric values are multiplied by when
+	// they are read as time.Duration, for instance, time.Second to treat
+	// `timeout: 30` as 30 seconds. If left empty, numeric values are
+	// treated as nanoseconds.
+	DurationUnit time.Duration
+
+	// IndexSlices makes slices in the conf map addressable by numeric
+	// indices in key paths. For instance, `servers.0.host` addresses the
+	// host key of the first map in the slice `servers`. Keys(), All()
+	// and the KeyMap expand slices into indexed keys.
+	IndexSlices bool
+
+	// CaseInsensitive folds all keys to lowercase when they are merged
+	// and key paths to lowercase when they are looked up, so that, for
+	// instance, `DB.Host` and `db.host` address the same value. Loading
+	// a config map that has keys that differ only in case returns an error.
+	CaseInsensitive bool
+
+	// OnDeprecated is called with every deprecated key path (see Alias())
+	// found in a config map that is merged, the key path it is moved to,
+	// and the name of the provider it came from (see Source()), for
+	// instance, to log a warning. It is called after the change that
+	// merges the config map, and can call the methods of the instance.
+	OnDeprecated func(old, new, src string)
+
+	// AliasConflict decides how a config map that has both a deprecated
+	// key path and its new key path is merged. Defaults to AliasPreferNew.
+	AliasConflict AliasConflict
+
+	// History is the number of changes to the config that are kept
+	// so that they can be undone with Rollback(). If left empty,
+	// no history is kept.
+	History int
 }
 
 // KeyMap represents a map of flattened delimited keys and the non-delimited
@@ -45,7 +148,30 @@ type UnmarshalConf struct {
 	// 	Type       string `koanf:"json"`
 	// }
 	// ```
-	FlatPaths     bool
+	FlatPaths bool
+
+	// ErrorUnused returns an error listing the full key paths of the keys
+	// in the conf map that do not map to any field in the struct, for
+	// instance, typos such as `sever.port`.
+	ErrorUnused bool
+
+	// ErrorUnset returns an error listing the full key paths of the fields
+	// in the struct that have no value in the conf map.
+	ErrorUnset bool
+
+	// Defaults fills fields whose keys are absent in the conf map with
+	// the values in their `default` struct tags. See StructDefaults().
+	Defaults bool
+
+	// DecodeHooks are additional mapstructure decode hooks that are
+	// composed after the DecoderConfig's hook, or DefaultDecodeHooks()
+	// if there's none. See StringToSliceHook(), TextUnmarshalerHook() etc.
+	// for the bundled hooks.
+	DecodeHooks []mapstructure.DecodeHookFunc
+
+	// DecoderConfig is a custom mapstructure DecoderConfig. Result and
+	// DecodeHook are set to the target struct and DefaultDecodeHooks()
+	// if they are left empty.
 	DecoderConfig *mapstructure.DecoderConfig
 }
 
@@ -53,44 +179,48 @@ type UnmarshalConf struct {
 // when specifying config key paths, for instance a . for `parent.child.key`
 // or a / for `parent/child/key`.
 func New(delim string) *Koanf {
+	return NewWithConf(Conf{
+		Delim: delim,
+	})
+}
+
+// NewWithConf returns a new instance of Koanf based on the Conf.
+func NewWithConf(conf Conf) *Koanf {
+	if conf.DurationUnit == 0 {
+		conf.DurationUnit = time.Nanosecond
+	}
+
 	return &Koanf{
-		delim:       delim,
+		conf:        conf,
 		confMap:     make(map[string]interface{}),
 		confMapFlat: make(map[string]interface{}),
 		keyMap:      make(KeyMap),
+		sources:     make(map[string]string),
+		names:       make(map[string]int),
 	}
 }
 
 // Load takes a Provider that either provides a parsed config map[string]interface{}
 // in which case pa (Parser) can be nil, or raw bytes to be parsed, where a Parser
-// can be provided to parse.
-func (ko *Koanf) Load(p Provider, pa Parser) error {
-	var (
-		mp  map[string]interface{}
-		err error
-	)
-
-	// No Parser is given. Call the Provider's Read() methid to get
-	// the config map.
-	if pa == nil {
-		mp, err = p.Read()
-		if err != nil {
-			return err
-		}
-	} else {
-		// There's a Parser. Get raw bytes from the Provider to parse.
-		b, err := p.ReadBytes()
-		if err != nil {
-			return err
-		}
-		mp, err = pa.Unmarshal(b)
-		if err != nil {
-			return err
-		}
-	}
+// can be provided to parse. Options such as WithPrefix() change how
+// the config map is merged. The config map is kept as a layer that can
+// be unloaded and reloaded (see Layers()). Errors are returned as
+// ProviderError, ParseError or MergeError.
+func (ko *Koanf) Load(p Provider, pa Parser, opts ...Option) error {
+	return ko.LoadContext(context.Background(), p, pa, opts...)
+}
 
-	ko.merge(mp)
-	return nil
+// LoadContext is like Load but stops reading the Provider and returns
+// ctx.Err() if the given context is cancelled or its deadline passes
+// before the read completes, for instance, to bound the time spent
+// fetching config from a remote source with context.WithTimeout().
+// The config is unchanged if the read doesn't complete. Providers that
+// implement ContextProvider stop reading. With other Providers, the read
+// is abandoned and left to complete in the background.
+func (ko *Koanf) LoadContext(ctx context.Context, p Provider, pa Parser, opts ...Option) error {
+	return ko.mutate(func() error {
+		return ko.load(ctx, p, pa, opts)
+	})
 }
 
 // Keys returns the slice of all flattened keys in the loaded configuration
@@ -104,6 +234,45 @@ func (ko *Koanf) Keys() []string {
 	return out
 }
 
+// KeysMatching returns the sorted list of key paths in the key map that
+// match the given pattern. The pattern is a key path where a `*` part
+// matches exactly one part of a key path and a `**` part matches any
+// number of parts, including none. For instance, `services.*.port` and
+// `**.timeout`. Other parts are matched with path.Match, which allows
+// wildcards within a part, such as `parent*.name`.
+func (ko *Koanf) KeysMatching(pattern string) []string {
+	pt := maps.SplitKey(ko.foldPath(pattern), ko.conf.Delim)
+
+	out := []string{}
+	for k, parts := range ko.keyMap {
+		if matchParts(pt, parts) {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Match returns a map of all key paths in the key map that match the given
+// pattern and their values. See KeysMatching() for the pattern syntax.
+func (ko *Koanf) Match(pattern string) map[string]interface{} {
+	keys := ko.KeysMatching(pattern)
+
+	out := make(map[string]interface{}, len(keys))
+	for _, k := range keys {
+		out[k] = ko.Get(k)
+	}
+	return out
+}
+
+// Query evaluates a JMESPath expression, for instance,
+// `servers[?enabled].host`, against the conf map and returns the result.
+// See https://jmespath.org for the expression syntax. Note that the query
+// runs on a copy of the conf map (see Raw()) where numeric types are float64.
+func (ko *Koanf) Query(expr string) (interface{}, error) {
+	return jmespath.Search(expr, ko.Raw())
+}
+
 // KeyMap returns a map of flattened keys and the individual parts of the
 // key as slices. eg: "parent.child.key" => ["parent", "child", "key"]
 func (ko *Koanf) KeyMap() KeyMap {
@@ -131,10 +300,12 @@ func (ko *Koanf) Raw() map[string]interface{} {
 
 // Sprint returns a key -> value string representation
 // of the config map with keys sorted alphabetically.
+// The values of sensitive key paths are masked (see MarkSensitive()).
 func (ko *Koanf) Sprint() string {
 	b := bytes.Buffer{}
 	for _, k := range ko.Keys() {
-		b.Write([]byte(fmt.Sprintf("%s -> %v\n", k, ko.confMapFlat[k])))
+		v := ko.redactValue(k, ko.confMapFlat[k])
+		b.Write([]byte(fmt.Sprintf("%s -> %v\n", k, v)))
 	}
 	return b.String()
 }
@@ -150,29 +321,200 @@ func (ko *Koanf) Print() {
 // For instance, if the loaded config has a path that looks like
 // parent.child.sub.a.b, `Cut("parent.child")` returns a new Koanf
 // instance with the config map `sub.a.b` where everything above
-// `parent.child` are cut out.
+// `parent.child` are cut out. Cuts of frozen instances are frozen
+// and share the config map with the instance.
 func (ko *Koanf) Cut(path string) *Koanf {
 	out := make(map[string]interface{})
 
-	// Cut only makes sense if the requested key path is a map.
-	if v, ok := ko.Get(path).(map[string]interface{}); ok {
+	// Cut only makes sense if the requested key path is a map. Frozen
+	// instances share it as it is, and others get a copy.
+	if v, ok := ko.subtree(path).(map[string]interface{}); ok {
 		out = v
 	}
 
-	n := New(ko.delim)
-	n.merge(out)
+	// Carry over the sources of the keys under the path.
+	var (
+		prefix  = ""
+		parts   []string
+		sources = make(map[string]string)
+	)
+	if path != "" {
+		prefix = ko.foldPath(path) + ko.conf.Delim
+		parts = maps.SplitKey(ko.foldPath(path), ko.conf.Delim)
+	}
+	for k, src := range ko.sources {
+		if strings.HasPrefix(k, prefix) {
+			sources[k[len(prefix):]] = src
+		}
+	}
+
+	// Children of frozen instances are frozen and share the config map.
+	if ko.readOnly {
+		n := NewWithConf(ko.conf)
+		n.confMap = out
+		n.sources = sources
+		n.flatten()
+		n.sensitive = cutPatterns(ko.sensitive, parts, ko.conf.Delim)
+		n.readOnly = true
+		return n
+	}
+
+	// The cut config map is the only layer of the new instance.
+	n := NewWithConf(ko.conf)
+	n.addLayer(&layer{
+		Layer:   Layer{Name: "cut"},
+		mp:      copyMap(out),
+		sources: sources,
+	}, -1)
+
+	// Carry over the sensitive patterns relative to the path.
+	n.sensitive = cutPatterns(ko.sensitive, parts, ko.conf.Delim)
 	return n
 }
 
-// Copy returns a copy of the Koanf instance.
+// Slices returns a list of Koanf instances constructed out of a
+// []map[string]interface{} slice addressed by the given key path.
+// For instance, `servers: [{host: a}, {host: b}]` returns
+// two Koanf instances, each with a `host` key. Non-map elements
+// in the slice are skipped. If the path is not a slice, an empty
+// list is returned. Slices of frozen instances are frozen.
+func (ko *Koanf) Slices(path string) []*Koanf {
+	out := []*Koanf{}
+
+	// Slices only makes sense if the requested key path is a slice.
+	v, ok := ko.subtree(path).([]interface{})
+	if !ok {
+		return out
+	}
+
+	for _, s := range v {
+		mp, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		n := NewWithConf(ko.conf)
+		if ko.readOnly {
+			n.confMap = mp
+			n.flatten()
+			n.readOnly = true
+		} else {
+			n.addLayer(&layer{Layer: Layer{Name: "slice"}, mp: copyMap(mp)}, -1)
+		}
+		out = append(out, n)
+	}
+	return out
+}
+
+// Copy returns a copy of the Koanf instance. Copies of
+// frozen instances are frozen and share the config.
 func (ko *Koanf) Copy() *Koanf {
-	return ko.Cut("")
+	if ko.readOnly {
+		return ko.Snapshot()
+	}
+
+	n := ko.Cut("")
+
+	// Layers are immutable and can be shared.
+	n.layers = append([]*layer{}, ko.layers...)
+	n.sets = append([]setOp{}, ko.sets...)
+	n.interp = ko.interp
+	for k, c := range ko.names {
+		n.names[k] = c
+	}
+	for old, new := range ko.aliases {
+		n.Alias(old, new)
+	}
+	return n
+}
+
+// Set sets the value at the given key path, replacing any existing value
+// or sub-tree at the path. Maps along the path that do not exist are
+// created. If Conf.IndexSlices is set, numeric parts of the path address
+// elements in existing slices, for instance, `servers.0.host`. Values set
+// with Set() take precedence over all layers (see Layers()).
+func (ko *Koanf) Set(path string, val interface{}) error {
+	return ko.mutate(func() error {
+		return ko.set(path, val)
+	})
+}
+
+// set sets the value at the given key path.
+func (ko *Koanf) set(path string, val interface{}) error {
+	if path == "" {
+		return errors.New("empty key path")
+	}
+
+	// Wrap the value in a map to convert any nested
+	// map[interface{}]interface{} to map[string]interface{}.
+	w := map[string]interface{}{"v": val}
+	maps.IntfaceKeysToStrings(w)
+	if ko.conf.CaseInsensitive {
+		f, err := foldKeys(w, nil, ko.conf.Delim)
+		if err != nil {
+			return fmt.Errorf("error setting %s: %v", path, err)
+		}
+		w = f
+	}
+
+	path = ko.foldPath(path)
+	parts := maps.SplitKey(path, ko.conf.Delim)
+
+	ko.own()
+	if err := setPath(ko.confMap, parts, copyValue(w["v"]), ko.conf.IndexSlices); err != nil {
+		return fmt.Errorf("error setting %s: %v", path, err)
+	}
+	ko.addSet(setOp{parts: parts, val: w["v"]})
+
+	// The value may change the interpolated values.
+	if ko.interp != nil {
+		return ko.recompute()
+	}
+
+	ko.flatten()
+	ko.setSources(parts, w["v"], "set")
+	return nil
+}
+
+// Delete removes the value at the given key path, and maps along the
+// path that are left empty. Like values set with Set(), deletions are
+// applied over all layers (see Layers()) until a value is set at the
+// path again.
+func (ko *Koanf) Delete(path string) error {
+	if path == "" {
+		return errors.New("empty key path")
+	}
+
+	return ko.mutate(func() error {
+		parts := maps.SplitKey(ko.foldPath(path), ko.conf.Delim)
+
+		ko.own()
+		maps.Delete(ko.confMap, parts)
+		ko.addSet(setOp{parts: parts, del: true})
+		if ko.interp != nil {
+			return ko.recompute()
+		}
+		ko.flatten()
+		return nil
+	})
 }
 
 // Merge merges the config map of a given Koanf instance into
-// the current instance.
-func (ko *Koanf) Merge(in *Koanf) {
-	ko.merge(in.Raw())
+// the current instance as a layer named `merge` (see Layers()).
+// The sources of the instance's keys are retained.
+func (ko *Koanf) Merge(in *Koanf) error {
+	l := &layer{
+		Layer:   Layer{Name: ko.uniqueName("merge")},
+		mp:      in.Raw(),
+		sources: make(map[string]string, len(in.sources)),
+	}
+	for k, src := range in.sources {
+		l.sources[ko.foldPath(k)] = src
+	}
+
+	return ko.mutate(func() error {
+		return ko.addLayer(l, -1)
+	})
 }
 
 // Marshal takes a Parser implementation and marshals the config map into bytes,
@@ -181,6 +523,13 @@ func (ko *Koanf) Marshal(p Parser) ([]byte, error) {
 	return p.Marshal(ko.All())
 }
 
+// MarshalPath marshals the nested config map under the given key path
+// with the path cut out, for instance, to write the config loaded with
+// WithPrefix() back to its own file.
+func (ko *Koanf) MarshalPath(path string, p Parser) ([]byte, error) {
+	return p.Marshal(ko.Cut(path).Raw())
+}
+
 // Unmarshal unmarshals a given key path into the given struct using
 // the mapstructure lib. If no path is specified, the whole map is unmarshalled.
 // `koanf` is the struct field tag used to match field names. To customize,
@@ -195,12 +544,25 @@ func (ko *Koanf) Unmarshal(path string, o interface{}) error {
 func (ko *Koanf) UnmarshalWithConf(path string, o interface{}, c UnmarshalConf) error {
 	if c.DecoderConfig == nil {
 		c.DecoderConfig = &mapstructure.DecoderConfig{
-			DecodeHook: mapstructure.ComposeDecodeHookFunc(
-				mapstructure.StringToTimeDurationHookFunc()),
 			Metadata:         nil,
-			Result:           o,
 			WeaklyTypedInput: true,
 		}
+	} else {
+		// Copy the custom DecoderConfig so that it's not mutated.
+		dc := *c.DecoderConfig
+		c.DecoderConfig = &dc
+	}
+
+	// Fill in the defaults that are not set in a custom DecoderConfig.
+	if c.DecoderConfig.Result == nil {
+		c.DecoderConfig.Result = o
+	}
+	if c.DecoderConfig.DecodeHook == nil {
+		c.DecoderConfig.DecodeHook = mapstructure.ComposeDecodeHookFunc(DefaultDecodeHooks()...)
+	}
+	if len(c.DecodeHooks) > 0 {
+		c.DecoderConfig.DecodeHook = mapstructure.ComposeDecodeHookFunc(
+			append([]mapstructure.DecodeHookFunc{c.DecoderConfig.DecodeHook}, c.DecodeHooks...)...)
 	}
 
 	if c.Tag == "" {
@@ -218,11 +580,28 @@ func (ko *Koanf) UnmarshalWithConf(path string, o interface{}, c UnmarshalConf)
 	mp := ko.Get(path)
 	if c.FlatPaths {
 		if f, ok := mp.(map[string]interface{}); ok {
-			fmp, _ := maps.Flatten(f, nil, ko.delim)
+			fmp, _ := maps.Flatten(f, nil, ko.conf.Delim)
 			mp = fmp
 		}
 	}
 
+	// Merge the conf map on top of the default values in the struct tags.
+	if c.Defaults {
+		def := StructDefaults(c.DecoderConfig.Result, c.DecoderConfig.TagName)
+		if f, ok := mp.(map[string]interface{}); ok {
+			maps.Merge(f, def)
+			mp = def
+		} else if mp == nil {
+			mp = def
+		}
+	}
+
+	if c.ErrorUnused || c.ErrorUnset {
+		if err := ko.checkStrict(path, mp, o, c); err != nil {
+			return err
+		}
+	}
+
 	return d.Decode(mp)
 }
 
@@ -235,11 +614,11 @@ func (ko *Koanf) Get(path string) interface{} {
 	}
 
 	// Does the path exist?
-	p, ok := ko.keyMap[path]
+	p, ok := ko.keyMap[ko.foldPath(path)]
 	if !ok {
 		return nil
 	}
-	res := maps.Search(ko.confMap, p)
+	res := ko.search(ko.confMap, p)
 
 	// Non-reference types are okay to return directly.
 	// Other types are "copied" with maps.Copy or json.Marshal
@@ -262,7 +641,7 @@ func (ko *Koanf) Get(path string) interface{} {
 
 // Exists returns true if the given key path exists in the conf map.
 func (ko *Koanf) Exists(path string) bool {
-	_, ok := ko.keyMap[path]
+	_, ok := ko.keyMap[ko.foldPath(path)]
 	return ok
 }
 
@@ -290,13 +669,131 @@ func (ko *Koanf) MapKeys(path string) []string {
 	return out
 }
 
-func (ko *Koanf) merge(c map[string]interface{}) {
+// prepare converts and folds the keys in the config map c from
+// the source src, or the sources of its individual keys if sources
+// is set, and applies aliases to it before it's merged.
+func (ko *Koanf) prepare(c map[string]interface{}, src string, sources map[string]string) (map[string]interface{}, error) {
 	maps.IntfaceKeysToStrings(c)
+	if ko.conf.CaseInsensitive {
+		f, err := foldKeys(c, nil, ko.conf.Delim)
+		if err != nil {
+			return nil, err
+		}
+		c = f
+	}
+
+	if err := ko.applyAliases(c, src, sources); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// mergeMap merges the prepared config map c into the conf map.
+func (ko *Koanf) mergeMap(c map[string]interface{}) {
+	ko.own()
 	maps.Merge(c, ko.confMap)
+	ko.flatten()
+}
+
+// setSources records src as the source of all the flattened
+// key paths in v, which is at the key path parts.
+func (ko *Koanf) setSources(parts []string, v interface{}, src string) {
+	mp, ok := v.(map[string]interface{})
+	if !ok || len(mp) == 0 {
+		ko.sources[maps.JoinKey(parts, ko.conf.Delim)] = src
+		return
+	}
+
+	var flat map[string]interface{}
+	if ko.conf.IndexSlices {
+		flat, _ = maps.FlattenSlices(mp, parts, ko.conf.Delim)
+	} else {
+		flat, _ = maps.Flatten(mp, parts, ko.conf.Delim)
+	}
+	for k := range flat {
+		ko.sources[k] = src
+	}
+}
+
+// Source returns the name of the provider that set the value at the
+// given key path, or of its nearest parent for paths inside values
+// such as slices. Providers that implement fmt.Stringer are named by
+// their String() and others by their type, followed by the location of
+// their source if they implement Locator, for instance,
+// `*file.File '/etc/app.yaml'`.
+// Values set with Set() have the source `set`. If the path does not exist
+// or has no known source, an empty string is returned.
+func (ko *Koanf) Source(path string) string {
+	path = ko.foldPath(path)
+	if _, ok := ko.keyMap[path]; !ok {
+		return ""
+	}
+
+	parts := maps.SplitKey(path, ko.conf.Delim)
+	for i := len(parts); i > 0; i-- {
+		if src, ok := ko.sources[maps.JoinKey(parts[:i], ko.conf.Delim)]; ok {
+			return src
+		}
+	}
+	return ""
+}
+
+// search searches the conf map mp for the key path parts, addressing
+// slice elements by their indices if Conf.IndexSlices is set.
+func (ko *Koanf) search(mp map[string]interface{}, parts []string) interface{} {
+	if ko.conf.IndexSlices {
+		return maps.SearchSlices(mp, parts)
+	}
+	return maps.Search(mp, parts)
+}
+
+// subtree returns the value at the given key path in the conf map of
+// a frozen instance without copying it, and a copy like Get() otherwise.
+func (ko *Koanf) subtree(path string) interface{} {
+	if !ko.readOnly {
+		return ko.Get(path)
+	}
+	if path == "" {
+		return ko.confMap
+	}
+
+	p, ok := ko.keyMap[ko.foldPath(path)]
+	if !ok {
+		return nil
+	}
+	return ko.search(ko.confMap, p)
+}
+
+// foldPath returns the lowercased key path if keys are case insensitive.
+func (ko *Koanf) foldPath(path string) string {
+	if ko.conf.CaseInsensitive {
+		return strings.ToLower(path)
+	}
+	return path
+}
+
+// flatten maintains a flattened version of the conf map and its key map.
+func (ko *Koanf) flatten() {
+	if ko.conf.IndexSlices {
+		ko.confMapFlat, ko.keyMap = maps.FlattenSlices(ko.confMap, nil, ko.conf.Delim)
+	} else {
+		ko.confMapFlat, ko.keyMap = maps.Flatten(ko.confMap, nil, ko.conf.Delim)
+	}
+	ko.keyMap = populateKeyParts(ko.keyMap, ko.conf.Delim)
+}
 
-	// Maintain a flattened version as well.
-	ko.confMapFlat, ko.keyMap = maps.Flatten(ko.confMap, nil, ko.delim)
-	ko.keyMap = populateKeyParts(ko.keyMap, ko.delim)
+// providerName returns the name of a Provider to record as a source,
+// with the location of its source if it implements Locator.
+func providerName(p Provider) string {
+	return describe(providerType(p), location(p))
+}
+
+// providerType returns the name of a Provider without its location.
+func providerType(p Provider) string {
+	if s, ok := p.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", p)
 }
 
 // toInt64 takes an interface value and if it is an integer type,
@@ -363,6 +860,173 @@ func toBool(v interface{}) (bool, error) {
 	return b, nil
 }
 
+// toDuration takes an interface value and if it is numeric, multiplies
+// it by unit and returns the time.Duration. Fractional values are
+// multiplied as floats, for instance, 1.5 seconds. If it's any other type,
+// forces it to a string and attempts to parse it with time.ParseDuration.
+func toDuration(v interface{}, unit time.Duration) (time.Duration, error) {
+	switch v.(type) {
+	case int, int8, int16, int32, int64:
+		i, _ := toInt64(v)
+		return time.Duration(i) * unit, nil
+	}
+	if f, err := toFloat64(v); err == nil {
+		return time.Duration(f * float64(unit)), nil
+	}
+
+	return time.ParseDuration(fmt.Sprintf("%v", v))
+}
+
+// toTime takes an interface value and parses it into a time.Time
+// with the given layout. The pseudo layouts LayoutUnix, LayoutUnixMilli
+// and LayoutUnixAuto treat numeric values as UNIX timestamps.
+func toTime(v interface{}, layout string) (time.Time, error) {
+	switch layout {
+	case LayoutUnix, LayoutUnixMilli, LayoutUnixAuto:
+		i, err := toInt64(v)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if layout == LayoutUnixMilli || (layout == LayoutUnixAuto && (i >= unixMilliMin || i <= -unixMilliMin)) {
+			return time.Unix(0, i*int64(time.Millisecond)), nil
+		}
+		return time.Unix(i, 0), nil
+	}
+
+	return time.Parse(layout, fmt.Sprintf("%v", v))
+}
+
+// setPath sets val at the given key path parts in the nested map mp,
+// creating maps along the path where necessary. If slices is true,
+// numeric parts address elements in existing slices.
+func setPath(mp map[string]interface{}, parts []string, val interface{}, slices bool) error {
+	var cur interface{} = mp
+	for i, k := range parts {
+		last := i == len(parts)-1
+
+		switch c := cur.(type) {
+		case map[string]interface{}:
+			if last {
+				c[k] = val
+				return nil
+			}
+
+			next := c[k]
+			if !isContainer(next, slices) {
+				next = make(map[string]interface{})
+				c[k] = next
+			}
+			cur = next
+
+		case []interface{}:
+			idx, err := strconv.Atoi(k)
+			if err != nil || idx < 0 || idx >= len(c) {
+				return fmt.Errorf("invalid slice index '%s'", k)
+			}
+			if last {
+				c[idx] = val
+				return nil
+			}
+
+			next := c[idx]
+			if !isContainer(next, slices) {
+				next = make(map[string]interface{})
+				c[idx] = next
+			}
+			cur = next
+		}
+	}
+	return nil
+}
+
+// foldKeys recursively returns a copy of the given map with all keys
+// lowercased. parts is the key path of the map used in error messages.
+// If two keys in a map differ only in case, an error is returned.
+func foldKeys(mp map[string]interface{}, parts []string, delim string) (map[string]interface{}, error) {
+	var (
+		out  = make(map[string]interface{}, len(mp))
+		orig = make(map[string]string, len(mp))
+	)
+	for k, v := range mp {
+		lk := strings.ToLower(k)
+		if o, ok := orig[lk]; ok {
+			a, b := maps.JoinKey(append(parts, o), delim), maps.JoinKey(append(parts, k), delim)
+			if a > b {
+				a, b = b, a
+			}
+			return nil, fmt.Errorf("keys '%s' and '%s' differ only in case", a, b)
+		}
+		orig[lk] = k
+
+		kp := make([]string, 0, len(parts)+1)
+		kp = append(kp, parts...)
+		kp = append(kp, lk)
+
+		switch c := v.(type) {
+		case map[string]interface{}:
+			f, err := foldKeys(c, kp, delim)
+			if err != nil {
+				return nil, err
+			}
+			v = f
+		case []interface{}:
+			s := make([]interface{}, len(c))
+			for i, item := range c {
+				if m, ok := item.(map[string]interface{}); ok {
+					f, err := foldKeys(m, append(kp, strconv.Itoa(i)), delim)
+					if err != nil {
+						return nil, err
+					}
+					item = f
+				}
+				s[i] = item
+			}
+			v = s
+		}
+		out[lk] = v
+	}
+	return out, nil
+}
+
+// isContainer returns true if v is a map, or a slice if slices is true.
+func isContainer(v interface{}, slices bool) bool {
+	switch v.(type) {
+	case map[string]interface{}:
+		return true
+	case []interface{}:
+		return slices
+	}
+	return false
+}
+
+// matchParts matches the parts of a key path against the parts of a pattern
+// where `*` matches a single part and `**` matches any number of parts.
+func matchParts(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+
+	if pattern[0] == "**" {
+		// Try consuming zero or more parts.
+		for i := 0; i <= len(parts); i++ {
+			if matchParts(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(parts) == 0 {
+		return false
+	}
+	if pattern[0] != "*" && pattern[0] != parts[0] {
+		if ok, _ := gopath.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+	}
+	return matchParts(pattern[1:], parts[1:])
+}
+
 // populateKeyParts iterates a key map and generates all possible
 // traveral paths. For instance, `parent.child.key` generates
 // `parent`, and `parent.child`.
@@ -370,7 +1034,7 @@ func populateKeyParts(m KeyMap, delim string) KeyMap {
 	out := make(KeyMap)
 	for _, parts := range m {
 		for i := range parts {
-			nk := strings.Join(parts[0:i+1], delim)
+			nk := maps.JoinKey(parts[0:i+1], delim)
 			if _, ok := out[nk]; ok {
 				continue
 			}
@@ -1,28 +1,35 @@
 package koanf_test
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/knadh/koanf"
 	"github.com/knadh/koanf/parsers/hcl"
 	"github.com/knadh/koanf/parsers/json"
+	"github.com/knadh/koanf/parsers/poserr"
 	"github.com/knadh/koanf/parsers/toml"
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/basicflag"
 	"github.com/knadh/koanf/providers/confmap"
+	"github.com/knadh/koanf/providers/defaults"
 	"github.com/knadh/koanf/providers/env"
 	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/providers/posflag"
 	"github.com/knadh/koanf/providers/rawbytes"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/assert"
 )
@@ -198,6 +205,12 @@ type testStructFlat struct {
 	Parent1Child1Grandchild1On  bool              `koanf:"parent1.child1.grandchild1.on"`
 }
 
+// testNode is a recursive struct.
+type testNode struct {
+	Name string    `koanf:"name" default:"node" validate:"required"`
+	Next *testNode `koanf:"next"`
+}
+
 type Case struct {
 	koanf    *koanf.Koanf
 	file     string
@@ -819,3 +832,1662 @@ func TestMustGetTypes(t *testing.T) {
 		assert.Equal(time.Date(1970, 1, 1, 0, 20, 34, 0, time.UTC), c.koanf.MustTime("parent1.id", "").UTC())
 	}
 }
+
+func TestDurationsTimes(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		k      = koanf.NewWithConf(koanf.Conf{Delim: delim, DurationUnit: time.Second})
+	)
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{
+		"timeout":       30,
+		"fractimeout":   1.5,
+		"strtimeout":    "3m",
+		"durations":     []interface{}{1, "2s", "3m"},
+		"baddurations":  []interface{}{1, "xxx"},
+		"durationmap":   map[string]interface{}{"a": 1, "b": "1h"},
+		"rfc3339":       "2019-01-01T10:20:30Z",
+		"date":          "2019-01-01",
+		"unix":          1546338030,
+		"unixmilli":     1546338030000,
+		"notatimestamp": "xxxx",
+	}, delim), nil))
+
+	// Durations.
+	assert.Equal(time.Second*30, k.Duration("timeout"))
+	assert.Equal(time.Millisecond*1500, k.Duration("fractimeout"))
+	assert.Equal(time.Minute*3, k.Duration("strtimeout"))
+	assert.Equal(time.Duration(0), k.Duration("xxxx"))
+	assert.Equal([]time.Duration{time.Second, time.Second * 2, time.Minute * 3}, k.Durations("durations"))
+	assert.Equal([]time.Duration{}, k.Durations("baddurations"))
+	assert.Equal([]time.Duration{}, k.Durations("xxxx"))
+	assert.Equal(map[string]time.Duration{"a": time.Second, "b": time.Hour}, k.DurationMap("durationmap"))
+	assert.Equal(map[string]time.Duration{}, k.DurationMap("xxxx"))
+	assert.Panics(func() { k.MustDurations("xxxx") })
+	assert.Panics(func() { k.MustDurationMap("xxxx") })
+
+	// Times.
+	var (
+		t1 = time.Date(2019, 1, 1, 10, 20, 30, 0, time.UTC)
+		t2 = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	)
+	assert.Equal(t1, k.TimeLayouts("rfc3339"))
+	assert.Equal(t2, k.TimeLayouts("date"))
+	assert.Equal(t1, k.TimeLayouts("unixmilli", koanf.LayoutUnixMilli).UTC())
+	assert.Equal(t1, k.TimeLayouts("unix").UTC())
+	assert.Equal(t1, k.TimeLayouts("unixmilli").UTC())
+	assert.Equal(t2, k.TimeLayouts("date", time.RFC3339, "2006-01-02"))
+	assert.Equal(time.Time{}, k.TimeLayouts("notatimestamp"))
+	assert.Equal(time.Time{}, k.TimeLayouts("xxxx"))
+	assert.Panics(func() { k.MustTimeLayouts("notatimestamp") })
+}
+
+func TestSlices(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		k      = koanf.New(delim)
+	)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`
+servers:
+  - host: a.example.com
+    port: 80
+    tls:
+      enabled: true
+  - orphan
+  - host: b.example.com
+    port: 8080
+`)), yaml.Parser()), "error loading raw bytes")
+
+	s := k.Slices("servers")
+	assert.Equal(2, len(s), "slices length mismatch")
+	assert.Equal("a.example.com", s[0].String("host"))
+	assert.Equal(80, s[0].Int("port"))
+	assert.True(s[0].Exists("tls.enabled"))
+	assert.Equal("b.example.com", s[1].String("host"))
+	assert.Equal(8080, s[1].Int("port"))
+	assert.False(s[1].Exists("tls"))
+
+	assert.Equal([]*koanf.Koanf{}, k.Slices("xxxx"))
+	assert.Equal([]*koanf.Koanf{}, cases[0].koanf.Slices("parent1"))
+	assert.Equal(0, len(cases[0].koanf.Slices("orphan")))
+}
+
+func TestIndexSlices(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		k      = koanf.NewWithConf(koanf.Conf{Delim: delim, IndexSlices: true})
+	)
+	assert.Nil(k.Load(file.Provider(mockJSON), json.Parser()), "error loading file")
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"servers": [{"host": "a", "port": 80}, {"host": "b"}]}`)), json.Parser()))
+
+	assert.Equal("a", k.String("servers.0.host"))
+	assert.Equal(80, k.Int("servers.0.port"))
+	assert.Equal("b", k.String("servers.1.host"))
+	assert.Equal("blue", k.String("orphan.1"))
+	assert.Equal([]string{"red", "blue", "orange"}, k.Strings("orphan"))
+	assert.True(k.Exists("servers.1"))
+	assert.True(k.Exists("servers"))
+	assert.False(k.Exists("servers.2"))
+	assert.Equal("", k.String("servers.2.host"))
+	assert.Contains(k.Keys(), "servers.1.host")
+	assert.Contains(k.Keys(), "parent1.child1.grandchild1.ids.2")
+	assert.Equal([]string{"servers", "0", "host"}, k.KeyMap()["servers.0.host"])
+
+	// Set into slices.
+	assert.Nil(k.Set("servers.1.port", 8080))
+	assert.Equal(8080, k.Int("servers.1.port"))
+	assert.Nil(k.Set("orphan.0", "green"))
+	assert.Equal([]string{"green", "blue", "orange"}, k.Strings("orphan"))
+	assert.NotNil(k.Set("servers.5.host", "c"))
+	assert.NotNil(k.Set("servers.x.host", "c"))
+
+	// Without IndexSlices, indices are not addressable.
+	assert.Equal("", cases[0].koanf.String("orphan.1"))
+	assert.False(cases[0].koanf.Exists("orphan.1"))
+}
+
+func TestSet(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		k      = koanf.New(delim)
+	)
+	assert.Nil(k.Load(file.Provider(mockJSON), json.Parser()), "error loading file")
+
+	assert.Nil(k.Set("parent1.name", "changed"))
+	assert.Equal("changed", k.String("parent1.name"))
+
+	// New nested keys.
+	assert.Nil(k.Set("new.child.key", 1))
+	assert.Equal(1, k.Int("new.child.key"))
+	assert.True(k.Exists("new.child"))
+
+	// Replace a leaf with a sub-tree and vice versa.
+	assert.Nil(k.Set("type.sub", "x"))
+	assert.Equal("x", k.String("type.sub"))
+	assert.Nil(k.Set("parent2", "flat"))
+	assert.Equal("flat", k.String("parent2"))
+	assert.False(k.Exists("parent2.name"))
+
+	// Maps with interface{} keys are converted.
+	assert.Nil(k.Set("imap", map[interface{}]interface{}{"a": 1}))
+	assert.Equal(1, k.Int("imap.a"))
+
+	assert.NotNil(k.Set("", 1))
+}
+
+func TestMatch(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		k      = cases[0].koanf
+	)
+
+	assert.Equal([]string{"parent1.name", "parent2.name"}, k.KeysMatching("*.name"))
+	assert.Equal([]string{"parent1.child1.name", "parent1.name", "parent2.child2.name", "parent2.name"},
+		k.KeysMatching("**.name"))
+	assert.Equal([]string{"parent1.child1.grandchild1.on", "parent2.child2.grandchild2.on"},
+		k.KeysMatching("parent*.**.on"))
+	assert.Equal([]string{"parent1.child1.type", "type"}, k.KeysMatching("**.type"))
+	assert.Equal([]string{"parent1.child1", "parent2.child2"}, k.KeysMatching("*.child?"))
+	assert.Equal([]string{"parent1", "parent2"}, k.KeysMatching("parent*"))
+	assert.Equal([]string{}, k.KeysMatching("*.xxx"))
+
+	assert.Equal(map[string]interface{}{
+		"parent1.id": float64(1234),
+		"parent2.id": float64(5678),
+	}, k.Match("*.id"))
+	assert.Equal(map[string]interface{}{}, k.Match("xxx.**"))
+}
+
+func TestQuery(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		k      = koanf.New(delim)
+	)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"servers": [
+		{"host": "a", "port": 80, "enabled": true},
+		{"host": "b", "port": 8080, "enabled": false},
+		{"host": "c", "port": 9090, "enabled": true}
+	]}`)), json.Parser()))
+
+	v, err := k.Query("servers[?enabled].host")
+	assert.Nil(err)
+	assert.Equal([]interface{}{"a", "c"}, v)
+
+	v, err = k.Query("servers[?port > `1000`].host")
+	assert.Nil(err)
+	assert.Equal([]interface{}{"b", "c"}, v)
+
+	v, err = k.Query("servers[0].port")
+	assert.Nil(err)
+	assert.Equal(float64(80), v)
+
+	v, err = k.Query("xxxx")
+	assert.Nil(err)
+	assert.Nil(v)
+
+	_, err = k.Query("servers[?")
+	assert.NotNil(err)
+}
+
+func TestEscapedKeys(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		k      = koanf.New(delim)
+	)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{
+		"hosts": {"example.com": {"ip": "1.1.1.1"}, "example": {"com": {"ip": "2.2.2.2"}}}
+	}`)), json.Parser()))
+
+	assert.Equal([]string{`hosts.example.com.ip`, `hosts.example\.com.ip`}, k.Keys())
+	assert.Equal("1.1.1.1", k.String(`hosts.example\.com.ip`))
+	assert.Equal("2.2.2.2", k.String(`hosts.example.com.ip`))
+	assert.Equal([]string{"hosts", "example.com"}, k.KeyMap()[`hosts.example\.com`])
+	assert.Equal([]string{"example", "example.com"}, k.MapKeys("hosts"))
+	assert.Equal("1.1.1.1", k.Cut(`hosts.example\.com`).String("ip"))
+
+	assert.Nil(k.Set(`hosts.example\.org.ip`, "3.3.3.3"))
+	assert.Equal(map[string]interface{}{"ip": "3.3.3.3"}, k.Get("hosts").(map[string]interface{})["example.org"])
+
+	// Escaped keys in providers.
+	os.Setenv(`KOANF_HOSTS.EXAMPLE\.COM.IP`, "4.4.4.4")
+	assert.Nil(k.Load(env.Provider("KOANF_", ".", func(s string) string {
+		return strings.ToLower(strings.TrimPrefix(s, "KOANF_"))
+	}), nil))
+	assert.Equal("4.4.4.4", k.String(`hosts.example\.com.ip`))
+	assert.Equal("2.2.2.2", k.String(`hosts.example.com.ip`))
+
+	// Flat paths with escaped keys are unflattened.
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{
+		`hosts.example\.com.ip`: "5.5.5.5",
+	}, "."), nil))
+	assert.Equal("5.5.5.5", k.String(`hosts.example\.com.ip`))
+
+	// Backslashes that don't precede the delimiter are not escapes.
+	k = koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"paths": {"C:\\tmp": "v"}}`)), json.Parser()))
+	assert.Equal([]string{`paths.C:\tmp`}, k.Keys())
+	assert.Equal("v", k.String(`paths.C:\tmp`))
+}
+
+func TestCaseInsensitive(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		k      = koanf.NewWithConf(koanf.Conf{Delim: delim, CaseInsensitive: true})
+	)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"DB": {"Host": "localhost", "Port": 5432}, "Servers": [{"Name": "a"}]}`)), json.Parser()))
+	assert.Equal("localhost", k.String("db.host"))
+	assert.Equal("localhost", k.String("DB.HOST"))
+	assert.Equal("localhost", k.String("Db.Host"))
+	assert.True(k.Exists("DB.port"))
+	assert.Equal([]string{"db.host", "db.port", "servers"}, k.Keys())
+	assert.Equal("a", k.Slices("SERVERS")[0].String("name"))
+
+	// Override with differently cased keys from another source.
+	os.Setenv("KOANFCI_DB.HOST", "remote")
+	assert.Nil(k.Load(env.Provider("KOANFCI_", ".", func(s string) string {
+		return strings.TrimPrefix(s, "KOANFCI_")
+	}), nil))
+	assert.Equal("remote", k.String("db.host"))
+	assert.Equal([]string{"db.host", "db.port", "servers"}, k.Keys())
+
+	assert.Nil(k.Set("DB.User", "admin"))
+	assert.Equal("admin", k.String("db.user"))
+
+	// Keys that differ only in case in the same source.
+	err := k.Load(rawbytes.Provider([]byte(`{"db": {"host": "a", "HOST": "b"}}`)), json.Parser())
+	assert.Error(err)
+	assert.Contains(err.Error(), "'db.HOST' and 'db.host'")
+
+	// Case sensitive by default.
+	k = koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"db": {"host": "a", "HOST": "b"}}`)), json.Parser()))
+	assert.Equal("a", k.String("db.host"))
+	assert.Equal("b", k.String("db.HOST"))
+	assert.False(k.Exists("DB.host"))
+}
+
+func TestInterpolate(t *testing.T) {
+	var (
+		assert = assert.New(t)
+		k      = koanf.New(delim)
+	)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{
+		"db": {"host": "localhost", "port": 5432, "name": "${app.name}_db"},
+		"app": {"name": "app", "port": "${db.port}"},
+		"url": "postgres://${db.host}:${db.port}/${db.name}",
+		"user": "${db.user:-admin}",
+		"nested": "${db.user:-${app.name}}",
+		"escaped": "$${db.host}",
+		"list": ["${db.host}", "b"],
+		"unterminated": "${db.host"
+	}`)), json.Parser()))
+
+	assert.Nil(k.Interpolate())
+	assert.Equal("postgres://localhost:5432/app_db", k.String("url"))
+	assert.Equal(float64(5432), k.Get("app.port"))
+	assert.Equal("admin", k.String("user"))
+	assert.Equal("app", k.String("nested"))
+	assert.Equal("${db.host}", k.String("escaped"))
+	assert.Equal([]string{"localhost", "b"}, k.Strings("list"))
+	assert.Equal("${db.host", k.String("unterminated"))
+
+	// Interpolating again is safe.
+	assert.Nil(k.Interpolate())
+	assert.Equal("${db.host}", k.String("escaped"))
+	assert.Equal("postgres://localhost:5432/app_db", k.String("url"))
+
+	// Resolvers.
+	os.Setenv("KOANF_INTERPOLATE", "fromenv")
+	f, err := ioutil.TempFile("", "koanf_secret")
+	assert.Nil(err)
+	f.Write([]byte("secret\n"))
+	f.Close()
+	defer os.Remove(f.Name())
+
+	k = koanf.New(delim)
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{
+		"env":     "${env:KOANF_INTERPOLATE}",
+		"file":    "${file:" + f.Name() + "}",
+		"default": "${env:KOANF_XXXX:-def}",
+	}, delim), nil))
+	assert.Nil(k.InterpolateWithConf(koanf.InterpolateConf{
+		Resolvers: map[string]koanf.Resolver{"env": koanf.EnvResolver, "file": koanf.FileResolver},
+	}))
+	assert.Equal("fromenv", k.String("env"))
+	assert.Equal("secret", k.String("file"))
+	assert.Equal("def", k.String("default"))
+
+	// Without resolvers, `env:` is a key path.
+	k = koanf.New(delim)
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{"a": "${env:KOANF_INTERPOLATE}"}, delim), nil))
+	assert.Error(k.Interpolate())
+
+	// Cycles and unresolved references.
+	k = koanf.New(delim)
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{
+		"a":  "${b}",
+		"b":  "x${a}",
+		"c":  "${c:-def}",
+		"d":  "${xxxx}",
+		"ok": "ok",
+	}, delim), nil))
+	err = k.Interpolate()
+	assert.Error(err)
+	assert.Contains(err.Error(), "a: cyclic reference: a -> b -> a")
+	assert.Contains(err.Error(), "c: cyclic reference: c -> c")
+	assert.Contains(err.Error(), "d: unresolved reference 'xxxx'")
+
+	// The conf map is not modified on errors.
+	assert.Equal("${b}", k.String("a"))
+
+	// Interpolation is applied again after changes.
+	k = koanf.New(delim)
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{"host": "h", "url": "x://${host}"}, delim), nil))
+	assert.Nil(k.Interpolate())
+	assert.Equal("x://h", k.String("url"))
+
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{"port": 1}, delim), nil, koanf.WithPriority(-1)))
+	assert.Equal("x://h", k.String("url"))
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{"host": "h2"}, delim), nil, koanf.WithName("host")))
+	assert.Equal("x://h2", k.String("url"))
+	assert.Nil(k.Set("host", "h3"))
+	assert.Equal("x://h3", k.String("url"))
+	assert.Nil(k.Unload("host"))
+	assert.Equal("x://h3", k.String("url"))
+
+	// Changes that leave references unresolved fail.
+	assert.Error(k.Delete("host"))
+	assert.Error(k.Load(confmap.Provider(map[string]interface{}{"x": "${xxxx}"}, delim), nil))
+	assert.Equal("x://h3", k.String("url"))
+	assert.Equal([]string{"host", "port", "url"}, k.Keys())
+	assert.Len(k.Layers(), 2)
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	assert := assert.New(t)
+
+	type server struct {
+		Host string `koanf:"host"`
+		Port int    `koanf:"port"`
+	}
+	type conf struct {
+		Name    string            `koanf:"name"`
+		Server  server            `koanf:"server"`
+		Servers []server          `koanf:"servers"`
+		Labels  map[string]string `koanf:"labels"`
+		Since   time.Time         `koanf:"since"`
+		Ignored string            `koanf:"-"`
+	}
+
+	k := koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{
+		"name": "app",
+		"server": {"host": "localhost", "sever": 1},
+		"servers": [{"host": "a", "port": 1}, {"host": "b", "prot": 2}],
+		"labels": {"a": "b"},
+		"example.com": true
+	}`)), json.Parser()))
+
+	// Unknown keys are ignored by default.
+	var c conf
+	assert.Nil(k.Unmarshal("", &c))
+	assert.Equal("localhost", c.Server.Host)
+
+	err := k.UnmarshalWithConf("", &c, koanf.UnmarshalConf{ErrorUnused: true})
+	assert.Error(err)
+	assert.Equal(`error unmarshalling: unknown keys: example\.com, server.sever, servers.1.prot`, err.Error())
+
+	err = k.UnmarshalWithConf("", &c, koanf.UnmarshalConf{ErrorUnset: true})
+	assert.Error(err)
+	assert.Equal(`error unmarshalling: unset fields: server.port, since`, err.Error())
+
+	err = k.UnmarshalWithConf("", &c, koanf.UnmarshalConf{ErrorUnused: true, ErrorUnset: true})
+	assert.Contains(err.Error(), "unknown keys: ")
+	assert.Contains(err.Error(), "; unset fields: ")
+
+	// Paths are prefixed with the unmarshalled path.
+	var s server
+	err = k.UnmarshalWithConf("server", &s, koanf.UnmarshalConf{ErrorUnused: true, ErrorUnset: true})
+	assert.Equal(`error unmarshalling: unknown keys: server.sever; unset fields: server.port`, err.Error())
+
+	// The mock configs map fully onto the test structs.
+	var ts testStruct
+	k = koanf.New(delim)
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{
+		"type":                           "x",
+		"empty":                          map[string]interface{}{},
+		"parent1.name":                   "p",
+		"parent1.id":                     1,
+		"parent1.child1.name":            "c",
+		"parent1.child1.type":            "x",
+		"parent1.child1.empty":           map[string]interface{}{},
+		"parent1.child1.grandchild1.ids": []int{1},
+		"parent1.child1.grandchild1.on":  true,
+	}, delim), nil))
+	assert.Nil(k.UnmarshalWithConf("", &ts, koanf.UnmarshalConf{ErrorUnused: true, ErrorUnset: true}))
+
+	var tsf testStructFlat
+	assert.Nil(k.UnmarshalWithConf("", &tsf, koanf.UnmarshalConf{FlatPaths: true, ErrorUnused: true, ErrorUnset: true}))
+	assert.Nil(k.Set("parent1.xxx", 1))
+	err = k.UnmarshalWithConf("", &tsf, koanf.UnmarshalConf{FlatPaths: true, ErrorUnused: true})
+	assert.Equal(`error unmarshalling: unknown keys: parent1.xxx`, err.Error())
+
+	// Recursive structs.
+	var n testNode
+	k = koanf.New(delim)
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{"name": "a", "next.name": "b"}, delim), nil))
+	err = k.UnmarshalWithConf("", &n, koanf.UnmarshalConf{ErrorUnset: true})
+	assert.Equal(`error unmarshalling: unset fields: next.next`, err.Error())
+}
+
+type testLevel int
+
+func (l *testLevel) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "debug":
+		*l = 1
+	case "info":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level: %s", b)
+	}
+	return nil
+}
+
+func TestUnmarshalDecodeHooks(t *testing.T) {
+	assert := assert.New(t)
+
+	type conf struct {
+		Timeout  time.Duration  `koanf:"timeout"`
+		Hosts    []string       `koanf:"hosts"`
+		Level    testLevel      `koanf:"level"`
+		LevelPtr *testLevel     `koanf:"level_ptr"`
+		Since    time.Time      `koanf:"since"`
+		Until    time.Time      `koanf:"until"`
+		IP       net.IP         `koanf:"ip"`
+		Net      net.IPNet      `koanf:"net"`
+		Re       *regexp.Regexp `koanf:"re"`
+	}
+
+	k := koanf.New(delim)
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{
+		"timeout":   "3s",
+		"hosts":     "a,b,c",
+		"level":     "info",
+		"level_ptr": "debug",
+		"since":     "2019-01-01",
+		"until":     1546300800,
+		"ip":        "127.0.0.1",
+		"net":       "10.0.0.0/8",
+		"re":        "^a+$",
+	}, delim), nil))
+
+	var c conf
+	assert.Nil(k.UnmarshalWithConf("", &c, koanf.UnmarshalConf{
+		DecodeHooks: []mapstructure.DecodeHookFunc{
+			koanf.StringToTimeHook(),
+			koanf.StringToIPNetHook(),
+			koanf.StringToRegexpHook(),
+			koanf.TextUnmarshalerHook(),
+			koanf.StringToSliceHook(","),
+		},
+	}))
+	assert.Equal(time.Second*3, c.Timeout)
+	assert.Equal([]string{"a", "b", "c"}, c.Hosts)
+	assert.Equal(testLevel(2), c.Level)
+	assert.Equal(testLevel(1), *c.LevelPtr)
+	assert.Equal(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), c.Since)
+	assert.Equal(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC), c.Until.UTC())
+	assert.Equal("127.0.0.1", c.IP.String())
+	assert.Equal("10.0.0.0/8", c.Net.String())
+	assert.True(c.Re.MatchString("aaa"))
+
+	// Hook errors.
+	assert.Nil(k.Set("level", "xxx"))
+	assert.Error(k.UnmarshalWithConf("", &c, koanf.UnmarshalConf{
+		DecodeHooks: []mapstructure.DecodeHookFunc{koanf.TextUnmarshalerHook()},
+	}))
+
+	// A custom DecoderConfig retains the default hooks and the target.
+	type durConf struct {
+		Timeout time.Duration `koanf:"timeout"`
+	}
+	var (
+		dc = &mapstructure.DecoderConfig{WeaklyTypedInput: true}
+		d  durConf
+	)
+	assert.Nil(k.UnmarshalWithConf("", &d, koanf.UnmarshalConf{DecoderConfig: dc}))
+	assert.Equal(time.Second*3, d.Timeout)
+	assert.Nil(dc.Result, "custom DecoderConfig was mutated")
+}
+
+func TestDefaults(t *testing.T) {
+	assert := assert.New(t)
+
+	type server struct {
+		Host    string        `koanf:"host" default:"localhost"`
+		Port    int           `koanf:"port" default:"8080"`
+		Timeout time.Duration `koanf:"timeout" default:"30s"`
+	}
+	type conf struct {
+		Name   string   `koanf:"name"`
+		Tags   []string `koanf:"tags" default:"a, b"`
+		Server server   `koanf:"server"`
+		Backup *server  `koanf:"backup"`
+	}
+
+	assert.Equal(map[string]interface{}{
+		"tags": []interface{}{"a", "b"},
+		"server": map[string]interface{}{
+			"host": "localhost", "port": "8080", "timeout": "30s",
+		},
+		"backup": map[string]interface{}{
+			"host": "localhost", "port": "8080", "timeout": "30s",
+		},
+	}, koanf.StructDefaults(conf{}, "koanf"))
+
+	k := koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"name": "app", "server": {"port": 9000}}`)), json.Parser()))
+
+	// Defaults are not applied unless asked for.
+	var c conf
+	assert.Nil(k.Unmarshal("", &c))
+	assert.Equal("", c.Server.Host)
+
+	c = conf{}
+	assert.Nil(k.UnmarshalWithConf("", &c, koanf.UnmarshalConf{Defaults: true, ErrorUnset: true}))
+	assert.Equal("app", c.Name)
+	assert.Equal([]string{"a", "b"}, c.Tags)
+	assert.Equal(server{Host: "localhost", Port: 9000, Timeout: time.Second * 30}, c.Server)
+	assert.Equal(&server{Host: "localhost", Port: 8080, Timeout: time.Second * 30}, c.Backup)
+
+	// Non-existent path.
+	var s server
+	assert.Nil(k.UnmarshalWithConf("xxxx", &s, koanf.UnmarshalConf{Defaults: true}))
+	assert.Equal(server{Host: "localhost", Port: 8080, Timeout: time.Second * 30}, s)
+
+	// Defaults as a provider.
+	k = koanf.New(delim)
+	assert.Nil(k.Load(defaults.Provider(conf{}, "koanf"), nil))
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"name": "app", "server": {"port": 9000}}`)), json.Parser()))
+	assert.Equal([]string{"backup.host", "backup.port", "backup.timeout", "name",
+		"server.host", "server.port", "server.timeout", "tags"}, k.Keys())
+	assert.Contains(k.Sprint(), "server.timeout -> 30s")
+	assert.Equal(9000, k.Int("server.port"))
+	assert.Equal(time.Second*30, k.Duration("server.timeout"))
+
+	// Recursive structs.
+	assert.Equal(map[string]interface{}{"name": "node"}, koanf.StructDefaults(testNode{}, ""))
+	var n testNode
+	assert.Nil(k.UnmarshalWithConf("xxxx", &n, koanf.UnmarshalConf{Defaults: true}))
+	assert.Equal(testNode{Name: "node"}, n)
+	assert.Nil(k.Load(defaults.Provider(testNode{}, "koanf"), nil))
+}
+
+func TestValidate(t *testing.T) {
+	assert := assert.New(t)
+
+	k := koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{
+		"name": "app",
+		"mode": "dev",
+		"servers": {"a": {"port": 8080}, "b": {"port": 70000}},
+		"tags": ["x"]
+	}`)), json.Parser()))
+	assert.Nil(k.Set("servers.a.host", "a.example.com"))
+
+	assert.Equal("*rawbytes.RawBytes", k.Source("servers.b.port"))
+	assert.Equal("", k.Source("servers.b"), "maps have no single source")
+	assert.Equal("set", k.Source("servers.a.host"))
+	assert.Equal("", k.Source("xxxx"))
+
+	assert.Nil(k.Validate(map[string]string{
+		"name":        "required,min=3,regex=^[a-z,]+$",
+		"mode":        "oneof=dev prod",
+		"servers.a.*": "required",
+		"tags":        "min=1",
+		"xxxx":        "max=1",
+	}))
+
+	err := k.Validate(map[string]string{
+		"servers.*.port": "min=1,max=65535",
+		"servers.*.host": "regex=^[a-z]+$",
+		"mode":           "oneof=prod",
+		"tags":           "min=2",
+		"db.host":        "required",
+	})
+	assert.Error(err)
+	errs, ok := err.(koanf.ValidationErrors)
+	assert.True(ok)
+	assert.Len(errs, 5)
+	assert.Equal(koanf.ValidationError{Key: "db.host", Rule: "required"}, errs[0])
+	assert.Equal(koanf.ValidationError{Key: "servers.b.port", Rule: "max=65535", Value: float64(70000),
+		Source: "*rawbytes.RawBytes"}, errs[3])
+	assert.Equal("validation failed: db.host: failed rule 'required'; "+
+		"mode: value 'dev' (from *rawbytes.RawBytes) failed rule 'oneof=prod'; "+
+		"servers.a.host: value 'a.example.com' (from set) failed rule 'regex=^[a-z]+$'; "+
+		"servers.b.port: value '70000' (from *rawbytes.RawBytes) failed rule 'max=65535'; "+
+		"tags: value '[x]' (from *rawbytes.RawBytes) failed rule 'min=2'", err.Error())
+
+	// Numeric strings are compared by value, and by length with minlen and maxlen.
+	assert.Nil(k.Set("port", "8080"))
+	assert.Nil(k.Validate(map[string]string{"port": "min=1024"}))
+	assert.Nil(k.Validate(map[string]string{"port": "minlen=4,maxlen=4", "tags": "maxlen=1"}))
+	assert.Equal("validation failed: port: value '8080' (from set) failed rule 'minlen=6'",
+		k.Validate(map[string]string{"port": "minlen=6"}).Error())
+
+	// Invalid rules.
+	assert.Error(k.Validate(map[string]string{"name": "xxxx"}))
+	assert.Error(k.Validate(map[string]string{"name": "min=x"}))
+	assert.Error(k.Validate(map[string]string{"name": "regex=("}))
+
+	// Struct tags.
+	type server struct {
+		Host string `koanf:"host" validate:"required"`
+		Port int    `koanf:"port" validate:"min=1,max=65535"`
+	}
+	type conf struct {
+		Name    string            `koanf:"name" validate:"required,oneof=app"`
+		Servers map[string]server `koanf:"servers"`
+		DB      server            `koanf:"db"`
+	}
+	err = k.ValidateStruct("", &conf{})
+	assert.Error(err)
+	assert.Equal("validation failed: db.host: failed rule 'required'", err.Error())
+
+	err = k.ValidateStruct("servers.b", server{})
+	assert.Error(err)
+	assert.Equal("validation failed: servers.b.host: failed rule 'required'; "+
+		"servers.b.port: value '70000' (from *rawbytes.RawBytes) failed rule 'max=65535'", err.Error())
+	assert.Nil(k.ValidateStruct("servers.a", server{}))
+
+	// min and max check the length of strings.
+	type pin struct {
+		Pin string `koanf:"pin" validate:"min=6"`
+	}
+	assert.Nil(k.Set("pin", "12"))
+	assert.Equal("validation failed: pin: value '12' (from set) failed rule 'minlen=6'",
+		k.ValidateStruct("", pin{}).Error())
+	assert.Nil(k.Set("pin", "123456"))
+	assert.Nil(k.ValidateStruct("", pin{}))
+
+	// Custom tags and flat paths.
+	type tagged struct {
+		Host string `yaml:"host" validate:"required"`
+		Port int    `yaml:"port" validate:"max=65535"`
+	}
+	err = k.ValidateStructWithConf("servers.b", tagged{}, koanf.UnmarshalConf{Tag: "yaml"})
+	assert.Equal("validation failed: servers.b.host: failed rule 'required'; "+
+		"servers.b.port: value '70000' (from *rawbytes.RawBytes) failed rule 'max=65535'", err.Error())
+
+	type flat struct {
+		Port int `koanf:"servers.b.port" validate:"max=65535"`
+	}
+	err = k.ValidateStructWithConf("", flat{}, koanf.UnmarshalConf{FlatPaths: true})
+	assert.Equal("validation failed: servers.b.port: value '70000' (from *rawbytes.RawBytes) failed rule 'max=65535'", err.Error())
+
+	// Recursive structs.
+	err = k.ValidateStruct("xxxx", &testNode{})
+	assert.Error(err)
+	assert.Equal("validation failed: xxxx.name: failed rule 'required'", err.Error())
+}
+
+func TestValidateSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	schema := []byte(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"required": ["name", "db"],
+		"additionalProperties": false,
+		"properties": {
+			"name": {"type": "string", "minLength": 3},
+			"mode": {"type": "string", "enum": ["dev", "prod"], "default": "dev"},
+			"db": {
+				"type": "object",
+				"required": ["host"],
+				"properties": {
+					"host": {"type": "string"},
+					"port": {"type": "integer", "maximum": 65535, "default": 5432}
+				}
+			}
+		}
+	}`)
+
+	k := koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"name": "ab", "db": {"port": 70000}, "xxxx": 1}`)), json.Parser()))
+
+	err := k.ValidateSchema(schema)
+	assert.Error(err)
+	errs, ok := err.(koanf.ValidationErrors)
+	assert.True(ok)
+	assert.Len(errs, 4)
+	assert.Equal("db.host", errs[0].Key)
+	assert.Equal(koanf.ValidationError{Key: "db.port", Rule: "Must be less than or equal to 65535",
+		Value: float64(70000), Source: "*rawbytes.RawBytes"}, errs[1])
+	assert.Equal("name", errs[2].Key)
+	assert.Equal("xxxx", errs[3].Key)
+	assert.False(k.Exists("mode"), "defaults are not applied unless asked for")
+
+	// Defaults fill absent keys.
+	k = koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"name": "app", "db": {"host": "localhost"}}`)), json.Parser()))
+	assert.Nil(k.ValidateSchemaWithConf(schema, koanf.SchemaConf{Defaults: true}))
+	assert.Equal("dev", k.String("mode"))
+	assert.Equal(5432, k.Int("db.port"))
+	assert.Equal("localhost", k.String("db.host"))
+	assert.Equal(koanf.SchemaSource, k.Source("db.port"))
+	assert.Equal("*rawbytes.RawBytes", k.Source("db.host"))
+
+	// Defaults don't override values.
+	assert.Nil(k.Set("mode", "prod"))
+	assert.Nil(k.ValidateSchemaWithConf(schema, koanf.SchemaConf{Defaults: true}))
+	assert.Equal("prod", k.String("mode"))
+
+	d, err := koanf.SchemaDefaults(schema)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"mode": "dev", "db": map[string]interface{}{"port": float64(5432)}}, d)
+
+	// Invalid schema.
+	assert.Error(k.ValidateSchema([]byte(`{"type": 1}`)))
+	assert.Error(k.ValidateSchema([]byte(`xxxx`)))
+}
+
+func TestRedact(t *testing.T) {
+	assert := assert.New(t)
+
+	k := koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{
+		"db": {"host": "localhost", "password": "secret"},
+		"api": {"auth": {"token": "abc", "user": "app"}},
+		"keys": {"private": {"a": "1", "b": "2"}}
+	}`)), json.Parser()))
+
+	assert.False(k.IsSensitive("db.password"))
+	k.MarkSensitive("*.password", "**.token", "keys.private")
+	assert.True(k.IsSensitive("db.password"))
+	assert.True(k.IsSensitive("api.auth.token"))
+	assert.True(k.IsSensitive("keys.private.a"))
+	assert.False(k.IsSensitive("db.host"))
+	assert.False(k.IsSensitive(""))
+
+	assert.Equal("api.auth.token -> ******\n"+
+		"api.auth.user -> app\n"+
+		"db.host -> localhost\n"+
+		"db.password -> ******\n"+
+		"keys.private.a -> ******\n"+
+		"keys.private.b -> ******\n", k.Sprint())
+
+	// Getters return the real values.
+	assert.Equal("secret", k.String("db.password"))
+	assert.Equal("secret", k.All()["db.password"])
+
+	all := k.AllRedacted()
+	assert.Equal(koanf.Redacted, all["db.password"])
+	assert.Equal("localhost", all["db.host"])
+
+	b, err := k.MarshalRedacted(json.Parser())
+	assert.Nil(err)
+	assert.NotContains(string(b), "secret")
+	assert.Contains(string(b), "localhost")
+
+	// Patterns apply relative to cut paths.
+	assert.Equal("host -> localhost\npassword -> ******\n", k.Cut("db").Sprint())
+	assert.Equal("token -> ******\nuser -> app\n", k.Cut("api.auth").Sprint())
+	assert.Equal("a -> ******\nb -> ******\n", k.Cut("keys.private").Sprint())
+	assert.True(k.Copy().IsSensitive("db.password"))
+	assert.False(k.Cut("db").IsSensitive("host"))
+
+	// Key paths within slices.
+	k = koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{
+		"servers": [{"host": "a", "password": "hunter2"}, {"host": "b", "opts": [{"token": "abc"}]}],
+		"list": [{"password": "x"}]
+	}`)), json.Parser()))
+	k.MarkSensitive("**.password", "**.token", "list.0")
+
+	assert.Equal("list -> [******]\n"+
+		"servers -> [map[host:a password:******] map[host:b opts:[map[token:******]]]]\n", k.Sprint())
+	all = k.AllRedacted()
+	assert.Equal([]interface{}{
+		map[string]interface{}{"host": "a", "password": koanf.Redacted},
+		map[string]interface{}{"host": "b", "opts": []interface{}{map[string]interface{}{"token": koanf.Redacted}}},
+	}, all["servers"])
+
+	b, err = k.MarshalRedacted(json.Parser())
+	assert.Nil(err)
+	assert.NotContains(string(b), "hunter2")
+	assert.NotContains(string(b), "abc")
+
+	// The values are not modified.
+	assert.Equal("hunter2", k.Get("servers").([]interface{})[0].(map[string]interface{})["password"])
+}
+
+func TestAlias(t *testing.T) {
+	assert := assert.New(t)
+
+	var deps []string
+	k := koanf.NewWithConf(koanf.Conf{
+		Delim: delim,
+		OnDeprecated: func(old, new, src string) {
+			deps = append(deps, fmt.Sprintf("%s -> %s (%s)", old, new, src))
+		},
+	})
+	k.Alias("http.addr", "server.listen")
+	k.Alias("db", "database")
+
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{
+		"http": {"addr": ":80", "timeout": 10},
+		"db": {"host": "localhost"}
+	}`)), json.Parser()))
+	assert.Equal([]string{"database.host", "http.timeout", "server.listen"}, k.Keys())
+	assert.Equal(":80", k.String("server.listen"))
+	assert.Equal("localhost", k.String("database.host"))
+	assert.Equal("*rawbytes.RawBytes", k.Source("server.listen"))
+	assert.Equal([]string{"db -> database (*rawbytes.RawBytes)",
+		"http.addr -> server.listen (*rawbytes.RawBytes)"}, deps)
+
+	// Deprecated keys from later providers override earlier new keys.
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{"http.addr": ":8080"}, "."), nil))
+	assert.Equal(":8080", k.String("server.listen"))
+	assert.False(k.Exists("http.addr"))
+
+	// Conflicts within a config map.
+	both := []byte(`{"http": {"addr": ":80"}, "server": {"listen": ":443"}}`)
+	for _, c := range []struct {
+		conflict koanf.AliasConflict
+		listen   string
+	}{
+		{koanf.AliasPreferNew, ":443"},
+		{koanf.AliasPreferOld, ":80"},
+	} {
+		k := koanf.NewWithConf(koanf.Conf{Delim: delim, AliasConflict: c.conflict})
+		k.Alias("http.addr", "server.listen")
+		assert.Nil(k.Load(rawbytes.Provider(both), json.Parser()))
+		assert.Equal(c.listen, k.String("server.listen"))
+		assert.Equal([]string{"server.listen"}, k.Keys())
+	}
+
+	k = koanf.NewWithConf(koanf.Conf{Delim: delim, AliasConflict: koanf.AliasError})
+	k.Alias("http.addr", "server.listen")
+	err := k.Load(rawbytes.Provider(both), json.Parser())
+	assert.Error(err)
+	assert.Equal("deprecated key 'http.addr' conflicts with 'server.listen'", err.(*koanf.MergeError).Err.Error())
+	assert.Empty(k.Keys())
+
+	// Merged instances report the sources of their deprecated keys, and
+	// copies retain the aliases.
+	deps = nil
+	in := koanf.New(delim)
+	assert.Nil(in.Load(rawbytes.Provider([]byte(`{"http": {"addr": ":81"}, "db": {"host": "a", "port": 1}}`)), json.Parser()))
+	k = koanf.NewWithConf(koanf.Conf{
+		Delim: delim,
+		OnDeprecated: func(old, new, src string) {
+			deps = append(deps, fmt.Sprintf("%s -> %s (%s)", old, new, src))
+		},
+	})
+	k.Alias("http.addr", "server.listen")
+	k.Alias("db", "database")
+	assert.Nil(k.Merge(in))
+	assert.Equal("*rawbytes.RawBytes", k.Source("server.listen"))
+	assert.Equal("*rawbytes.RawBytes", k.Source("database.port"))
+	assert.Equal([]string{"db -> database (*rawbytes.RawBytes)",
+		"http.addr -> server.listen (*rawbytes.RawBytes)"}, deps)
+
+	c := k.Copy()
+	assert.Nil(c.Load(rawbytes.Provider([]byte(`{"http": {"addr": ":82"}}`)), json.Parser()))
+	assert.Equal([]string{"database.host", "database.port", "server.listen"}, c.Keys())
+	assert.Equal(":82", c.String("server.listen"))
+
+	// OnDeprecated can call the methods of the instance.
+	var listen string
+	k = koanf.NewWithConf(koanf.Conf{
+		Delim: delim,
+		OnDeprecated: func(old, new, src string) {
+			listen = k.Snapshot().String(new)
+			assert.Nil(k.Set("deprecated", true))
+		},
+	})
+	k.Alias("http.addr", "server.listen")
+	done := make(chan error)
+	go func() {
+		done <- k.Load(rawbytes.Provider([]byte(`{"http": {"addr": ":80"}}`)), json.Parser())
+	}()
+	select {
+	case err := <-done:
+		assert.Nil(err)
+	case <-time.After(time.Second):
+		t.Fatal("OnDeprecated deadlocked")
+	}
+	assert.Equal(":80", listen)
+	assert.True(k.Bool("deprecated"))
+}
+
+func TestLoadPrefix(t *testing.T) {
+	assert := assert.New(t)
+
+	k := koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"cache": {"ttl": 10}}`)), json.Parser()))
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"host": "localhost", "db": {"index": 1}}`)), json.Parser(),
+		koanf.WithPrefix("cache.redis")))
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{}`)), json.Parser(), koanf.WithPrefix("xxxx")))
+
+	assert.Equal([]string{"cache.redis.db.index", "cache.redis.host", "cache.ttl"}, k.Keys())
+	assert.Equal("localhost", k.String("cache.redis.host"))
+	assert.Equal(10, k.Int("cache.ttl"))
+	assert.Equal("*rawbytes.RawBytes", k.Source("cache.redis.host"))
+
+	// Export the subtree.
+	b, err := k.MarshalPath("cache.redis", json.Parser())
+	assert.Nil(err)
+	out, err := json.Parser().Unmarshal(b)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"host": "localhost", "db": map[string]interface{}{"index": float64(1)}}, out)
+
+	// The subtree loads back to the same keys.
+	k2 := koanf.New(delim)
+	assert.Nil(k2.Load(rawbytes.Provider(b), json.Parser(), koanf.WithPrefix("cache.redis")))
+	assert.Equal([]string{"cache.redis.db.index", "cache.redis.host"}, k2.Keys())
+	assert.Equal(k.Cut("cache.redis").All(), k2.Cut("cache.redis").All())
+}
+
+func TestLoadFilterTransform(t *testing.T) {
+	assert := assert.New(t)
+
+	b := []byte(`{
+		"app": {"name": " app ", "debug": true},
+		"db": {"host": "localhost", "password": "secret"},
+		"internal": {"id": 1}
+	}`)
+
+	k := koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider(b), json.Parser(),
+		koanf.WithInclude("app", "db"),
+		koanf.WithExclude("db.password"),
+		koanf.WithFilter(func(key string, val interface{}) bool {
+			return val != true
+		}),
+		koanf.WithTransform(func(key string, val interface{}) (string, interface{}) {
+			if s, ok := val.(string); ok {
+				val = strings.TrimSpace(s)
+			}
+			return strings.Replace(key, "db.", "database.", 1), val
+		}),
+		koanf.WithPrefix("svc")))
+	assert.Equal([]string{"svc.app.name", "svc.database.host"}, k.Keys())
+	assert.Equal("app", k.String("svc.app.name"))
+
+	// Dropping keys with the transform.
+	k = koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider(b), json.Parser(),
+		koanf.WithTransform(func(key string, val interface{}) (string, interface{}) {
+			if strings.HasPrefix(key, "internal") {
+				return "", nil
+			}
+			return key, val
+		})))
+	assert.Equal([]string{"app.debug", "app.name", "db.host", "db.password"}, k.Keys())
+
+	// Options apply to providers with Read() too.
+	k = koanf.New(delim)
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{
+		"a.b": 1, "a.c": 2, "ab": 3,
+	}, "."), nil, koanf.WithInclude("a")))
+	assert.Equal([]string{"a.b", "a.c"}, k.Keys())
+}
+
+func TestLayers(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "koanf_layers")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	var (
+		fa = filepath.Join(dir, "a.json")
+		fb = filepath.Join(dir, "b.json")
+	)
+	assert.Nil(ioutil.WriteFile(fa, []byte(`{"name": "a", "a": 1, "port": 80}`), 0600))
+	assert.Nil(ioutil.WriteFile(fb, []byte(`{"name": "b", "b": 1}`), 0600))
+
+	var (
+		na = fmt.Sprintf("*file.File '%s'", fa)
+		nb = fmt.Sprintf("*file.File '%s'", fb)
+	)
+
+	k := koanf.New(delim)
+	pa := file.Provider(fa)
+	assert.Nil(k.Load(pa, json.Parser()))
+	assert.Nil(k.Load(file.Provider(fb), json.Parser()))
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{"name": "env"}, "."), nil,
+		koanf.WithName("env"), koanf.WithPriority(-1)))
+	assert.Equal([]koanf.Layer{{Name: "env", Priority: -1}, {Name: na}, {Name: nb}}, k.Layers())
+	assert.Equal("b", k.String("name"))
+
+	// A higher priority layer loaded earlier takes precedence.
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{"name": "env"}, "."), nil,
+		koanf.WithName("env"), koanf.WithPriority(10)))
+	assert.Equal("env", k.String("name"))
+	assert.Equal("*confmap.Confmap", k.Source("name"))
+	assert.Len(k.Layers(), 3)
+
+	// Set values take precedence over all layers.
+	assert.Nil(k.Set("port", 8080))
+	assert.Nil(k.Unload("env"))
+	assert.Equal("b", k.String("name"))
+	assert.Equal(8080, k.Int("port"))
+	assert.Equal([]string{"a", "b", "name", "port"}, k.Keys())
+
+	// Reload re-reads the provider in place.
+	assert.Nil(ioutil.WriteFile(fa, []byte(`{"name": "a2", "c": 1}`), 0600))
+	assert.Nil(k.Reload(na))
+	assert.Equal([]string{"b", "c", "name", "port"}, k.Keys())
+	assert.Equal("b", k.String("name"))
+
+	// Loading the same provider instance replaces its layer.
+	assert.Nil(ioutil.WriteFile(fa, []byte(`{"name": "a3"}`), 0600))
+	assert.Nil(k.Load(pa, json.Parser()))
+	assert.Equal([]koanf.Layer{{Name: na}, {Name: nb}}, k.Layers())
+	assert.Equal([]string{"b", "name", "port"}, k.Keys())
+	assert.Equal("b", k.String("name"))
+	assert.Equal(nb, k.Source("name"))
+
+	assert.Nil(k.Unload(nb))
+	assert.Equal("a3", k.String("name"))
+	assert.Equal(na, k.Source("name"))
+
+	// Copies retain the layers.
+	c := k.Copy()
+	assert.Nil(c.Unload(na))
+	assert.Equal([]string{"port"}, c.Keys())
+	assert.Equal("a3", k.String("name"))
+
+	// New instances of a provider for the same source replace its layer.
+	for i := 0; i < 10; i++ {
+		assert.Nil(k.Load(file.Provider(fa), json.Parser()))
+		assert.Nil(k.Load(rawbytes.Provider([]byte(`{"x": 1}`)), json.Parser()))
+	}
+	assert.Equal([]koanf.Layer{{Name: na}, {Name: "*rawbytes.RawBytes"}}, k.Layers())
+
+	// Other providers with the same name are numbered.
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"x": 2}`)), json.Parser()))
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"x": 3}`)), json.Parser()))
+	assert.Nil(k.Unload("*rawbytes.RawBytes:2"))
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"x": 4}`)), json.Parser()))
+	assert.Equal([]koanf.Layer{{Name: na}, {Name: "*rawbytes.RawBytes"},
+		{Name: "*rawbytes.RawBytes:3"}, {Name: "*rawbytes.RawBytes:4"}}, k.Layers())
+	assert.Equal(4, k.Int("x"))
+	assert.Nil(k.Unload("*rawbytes.RawBytes:3"))
+	assert.Nil(k.Unload("*rawbytes.RawBytes:4"))
+	assert.Nil(k.Unload("*rawbytes.RawBytes"))
+
+	// Provider values holding uncomparable values are not compared.
+	vp := valProvider{data: map[string]int{"a": 1}}
+	assert.Nil(k.Load(vp, nil))
+	assert.Nil(k.Load(vp, nil))
+	assert.Nil(k.Load(valProvider{data: map[string]int{"a": 2}}, nil))
+	assert.Equal([]koanf.Layer{{Name: na}, {Name: "koanf_test.valProvider"},
+		{Name: "koanf_test.valProvider:2"}}, k.Layers())
+	assert.Nil(k.Unload("koanf_test.valProvider"))
+	assert.Nil(k.Unload("koanf_test.valProvider:2"))
+
+	// Errors.
+	assert.Error(k.Unload("xxxx"))
+	assert.Error(k.Reload("xxxx"))
+	assert.Nil(k.Merge(c))
+	assert.Error(k.Reload("merge"))
+	assert.Error(k.Load(nil, nil))
+}
+
+func TestSnapshotRollback(t *testing.T) {
+	assert := assert.New(t)
+
+	k := koanf.NewWithConf(koanf.Conf{Delim: delim, History: 2})
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"db": {"host": "a", "port": 1}}`)), json.Parser(),
+		koanf.WithName("file")))
+
+	s := k.Snapshot()
+	assert.Nil(k.Set("db.host", "b"))
+	assert.Nil(k.Load(confmap.Provider(map[string]interface{}{"db.port": 2, "x": 1}, "."), nil))
+	assert.Nil(k.Interpolate())
+
+	// The snapshot is unaffected by changes.
+	assert.Equal("a", s.String("db.host"))
+	assert.Equal(1, s.Int("db.port"))
+	assert.Equal([]string{"db.host", "db.port"}, s.Keys())
+	assert.Equal("*rawbytes.RawBytes", s.Source("db.host"))
+	assert.Equal([]koanf.Layer{{Name: "file"}}, s.Layers())
+	assert.Equal("b", k.String("db.host"))
+	assert.Equal(2, k.Int("db.port"))
+
+	// Snapshots are read-only.
+	assert.Error(s.Set("db.host", "c"))
+	assert.Error(s.Load(confmap.Provider(map[string]interface{}{"x": 1}, "."), nil))
+	assert.Error(s.Merge(k))
+	assert.Error(s.Unload("file"))
+	assert.Error(s.Rollback(1))
+	assert.Equal("a", s.String("db.host"))
+
+	// Sensitive patterns marked later are not shared.
+	k2 := koanf.New(delim)
+	assert.Nil(k2.Load(rawbytes.Provider([]byte(`{"a": 1, "b": 2, "c": 3}`)), json.Parser()))
+	k2.MarkSensitive("x", "y", "z")
+	ks := k2.Snapshot()
+	ks.MarkSensitive("b")
+	k2.MarkSensitive("a")
+	assert.Equal("a -> 1\nb -> ******\nc -> 3\n", ks.Sprint())
+	assert.Equal("a -> ******\nb -> 2\nc -> 3\n", k2.Sprint())
+
+	// Changes made to the instance don't leak into the history.
+	s2 := k.Snapshot()
+	assert.Nil(k.Set("db.host", "c"))
+	assert.Equal("b", s2.String("db.host"))
+
+	// Only History changes are kept.
+	assert.Error(k.Rollback(3))
+	assert.Error(k.Rollback(0))
+	assert.Nil(k.Rollback(2))
+	assert.Equal("b", k.String("db.host"))
+	assert.Equal(2, k.Int("db.port"))
+	assert.Equal([]string{"db.host", "db.port", "x"}, k.Keys())
+	assert.Equal("set", k.Source("db.host"))
+	assert.Equal([]koanf.Layer{{Name: "file"}, {Name: "*confmap.Confmap"}}, k.Layers())
+	assert.Error(k.Rollback(1))
+
+	// Failed changes are not recorded.
+	assert.Error(k.Unload("xxxx"))
+	assert.Error(k.Rollback(1))
+
+	// Restored layers can be unloaded and reloaded.
+	assert.Nil(k.Set("db.port", 3))
+	assert.Nil(k.Rollback(1))
+	assert.Equal(2, k.Int("db.port"))
+	assert.Nil(k.Unload("*confmap.Confmap"))
+	assert.Nil(k.Reload("file"))
+	assert.Equal(1, k.Int("db.port"))
+	assert.Equal("b", k.String("db.host"))
+
+	// No history by default.
+	k = koanf.New(delim)
+	assert.Nil(k.Set("a", 1))
+	assert.Error(k.Rollback(1))
+}
+
+func TestSnapshotConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	k := koanf.NewWithConf(koanf.Conf{Delim: delim, History: 2})
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"n": 0}`)), json.Parser(), koanf.WithName("file")))
+
+	// Snapshots are consistent while the config is reloaded.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s := k.Snapshot()
+				if s.Int("n") != s.Int("m") {
+					t.Errorf("inconsistent snapshot: %s", s.Sprint())
+				}
+			}
+		}()
+	}
+
+	for i := 1; i <= 100; i++ {
+		b := []byte(fmt.Sprintf(`{"n": %d, "m": %d}`, i, i))
+		assert.Nil(k.Load(rawbytes.Provider(b), json.Parser(), koanf.WithName("file")))
+	}
+	assert.Nil(k.Rollback(1))
+	wg.Wait()
+	assert.Equal(99, k.Int("n"))
+}
+
+func TestBind(t *testing.T) {
+	assert := assert.New(t)
+
+	type server struct {
+		Host    string        `koanf:"host"`
+		Port    int           `koanf:"port"`
+		Timeout time.Duration `koanf:"timeout"`
+	}
+
+	k := koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"server": {"host": "a", "port": 80, "timeout": "1s"}}`)),
+		json.Parser(), koanf.WithName("file")))
+
+	var (
+		errs    []error
+		updates int
+		s       server
+	)
+	b, err := k.Bind("server", &s, koanf.BindConf{
+		UnmarshalConf: koanf.UnmarshalConf{ErrorUnused: true},
+		OnError:       func(err error) { errs = append(errs, err) },
+		OnUpdate:      func(v interface{}) { updates++ },
+	})
+	assert.Nil(err)
+	assert.Equal(&server{Host: "a", Port: 80, Timeout: time.Second}, b.Load())
+
+	// Readers see every published struct.
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			assert.NotNil(b.Load().(*server))
+		}
+		done <- true
+	}()
+
+	assert.Nil(k.Set("server.port", 8080))
+	<-done
+	assert.Equal(&server{Host: "a", Port: 8080, Timeout: time.Second}, b.Load())
+	assert.Equal(server{Host: "a", Port: 80, Timeout: time.Second}, s, "the original target is not changed")
+	assert.Equal(1, updates)
+	assert.Nil(b.Err())
+
+	// Decode errors retain the last good struct.
+	assert.Nil(k.Set("server.xxxx", 1))
+	assert.Equal(8080, b.Load().(*server).Port)
+	assert.Error(b.Err())
+	assert.Len(errs, 1)
+
+	assert.Nil(k.Set("server", map[string]interface{}{"host": "b", "port": "x"}))
+	assert.Equal("a", b.Load().(*server).Host)
+	assert.Len(errs, 2)
+
+	assert.Nil(k.Set("server", map[string]interface{}{"host": "b"}))
+	assert.Equal(&server{Host: "b"}, b.Load())
+	assert.Nil(b.Err())
+	assert.Equal(2, updates)
+
+	// Closed bindings are not updated.
+	b.Close()
+	assert.Nil(k.Set("server.host", "c"))
+	assert.Equal("b", b.Load().(*server).Host)
+
+	// Errors.
+	_, err = k.Bind("server", s, koanf.BindConf{})
+	assert.Error(err)
+	_, err = k.Bind("server", &s, koanf.BindConf{UnmarshalConf: koanf.UnmarshalConf{ErrorUnset: true}})
+	assert.Error(err)
+}
+
+func TestBindConcurrent(t *testing.T) {
+	assert := assert.New(t)
+
+	type server struct {
+		Port int `koanf:"port"`
+	}
+
+	k := koanf.New(delim)
+	assert.Nil(k.Set("server.port", 1))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				var s server
+				b, err := k.Bind("server", &s, koanf.BindConf{})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				b.Close()
+			}
+		}()
+	}
+
+	var s server
+	b, err := k.Bind("server", &s, koanf.BindConf{})
+	assert.Nil(err)
+	for i := 0; i < 200; i++ {
+		assert.Nil(k.Set("server.port", i))
+	}
+	wg.Wait()
+	assert.Equal(199, b.Load().(*server).Port)
+}
+
+func TestDelete(t *testing.T) {
+	assert := assert.New(t)
+
+	k := koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"db": {"host": "a", "port": 1}, "name": "app"}`)), json.Parser(),
+		koanf.WithName("file")))
+
+	assert.Nil(k.Delete("db.host"))
+	assert.Nil(k.Delete("xxxx.yyyy"))
+	assert.Equal([]string{"db.port", "name"}, k.Keys())
+	assert.Error(k.Delete(""))
+
+	// Deletions are applied over reloaded layers.
+	assert.Nil(k.Reload("file"))
+	assert.Equal([]string{"db.port", "name"}, k.Keys())
+
+	// Empty maps are removed.
+	assert.Nil(k.Delete("db.port"))
+	assert.Equal([]string{"name"}, k.Keys())
+	assert.False(k.Exists("db"))
+
+	// Setting a value again overrides the deletion.
+	assert.Nil(k.Set("db.host", "b"))
+	assert.Nil(k.Reload("file"))
+	assert.Equal([]string{"db.host", "name"}, k.Keys())
+	assert.Equal("b", k.String("db.host"))
+}
+
+func TestFreeze(t *testing.T) {
+	assert := assert.New(t)
+
+	k := koanf.New(delim)
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"db": {"host": "a", "password": "x"}, "name": "app",
+		"servers": [{"host": "s1"}]}`)), json.Parser(), koanf.WithName("file")))
+	k.MarkSensitive("**.password")
+	assert.False(k.Frozen())
+
+	k.Freeze()
+	assert.True(k.Frozen())
+	assert.Error(k.Load(confmap.Provider(map[string]interface{}{"x": 1}, "."), nil))
+	assert.Error(k.Merge(koanf.New(delim)))
+	assert.Error(k.Set("name", "x"))
+	assert.Error(k.Delete("name"))
+	assert.Error(k.Unload("file"))
+	assert.Error(k.Reload("file"))
+	assert.Error(k.Interpolate())
+	assert.Equal([]string{"db.host", "db.password", "name", "servers"}, k.Keys())
+
+	// Children are frozen.
+	c := k.Cut("db")
+	assert.True(c.Frozen())
+	assert.Equal([]string{"host", "password"}, c.Keys())
+	assert.Equal("a", c.String("host"))
+	assert.Equal("*rawbytes.RawBytes", c.Source("host"))
+	assert.Contains(c.Sprint(), "password -> ******")
+	assert.Error(c.Set("host", "b"))
+
+	cp := k.Copy()
+	assert.True(cp.Frozen())
+	assert.Equal(k.All(), cp.All())
+	assert.Equal(k.Layers(), cp.Layers())
+	assert.Error(cp.Set("name", "b"))
+
+	sl := k.Slices("servers")
+	assert.Len(sl, 1)
+	assert.True(sl[0].Frozen())
+	assert.Equal("s1", sl[0].String("host"))
+
+	// Children share the config map without copying it to JSON types.
+	k2 := koanf.New(delim)
+	assert.Nil(k2.Set("db.port", 5432))
+	assert.Nil(k2.Set("db.hosts", []interface{}{"a"}))
+	assert.Nil(k2.Set("servers", []interface{}{map[string]interface{}{"port": int64(80)}}))
+	k2.Freeze()
+	assert.IsType(int(0), k2.Cut("db").Get("port"))
+	assert.IsType(int(0), k2.Cut("").Get("db.port"))
+	assert.IsType(int64(0), k2.Slices("servers")[0].Get("port"))
+	assert.Equal([]string{"a"}, k2.Cut("db").Strings("hosts"))
+
+	// Reads still work.
+	var out struct {
+		Name string `koanf:"name"`
+	}
+	assert.Nil(k.Unmarshal("", &out))
+	assert.Equal("app", out.Name)
+	assert.True(k.Snapshot().Frozen())
+}
+
+// slowProvider is a Provider that takes a while to read.
+type slowProvider struct {
+	delay time.Duration
+}
+
+func (s slowProvider) ReadBytes() ([]byte, error) {
+	time.Sleep(s.delay)
+	return []byte(`{"name": "slow"}`), nil
+}
+
+func (s slowProvider) Read() (map[string]interface{}, error) {
+	time.Sleep(s.delay)
+	return map[string]interface{}{"name": "slow"}, nil
+}
+
+func (s slowProvider) Watch(cb func(event interface{}, err error)) error {
+	return nil
+}
+
+func TestLoadContext(t *testing.T) {
+	assert := assert.New(t)
+
+	k := koanf.New(delim)
+	assert.Nil(k.LoadContext(context.Background(), slowProvider{delay: time.Millisecond}, nil))
+	assert.Equal("slow", k.String("name"))
+
+	// Reads of providers without context support are abandoned.
+	k = koanf.New(delim)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(context.DeadlineExceeded, k.LoadContext(ctx, slowProvider{delay: time.Second}, json.Parser()))
+	assert.Equal(context.DeadlineExceeded, k.LoadContext(ctx, slowProvider{delay: time.Second}, nil))
+	assert.Empty(k.Keys())
+	assert.Empty(k.Layers())
+
+	// Context providers.
+	ctx, cancel = context.WithCancel(context.Background())
+	assert.Nil(k.LoadContext(ctx, file.Provider(mockJSON), json.Parser()))
+	assert.Equal("parent1", k.String("parent1.name"))
+	cancel()
+	assert.Equal(context.Canceled, k.LoadContext(ctx, file.Provider(mockYAML), yaml.Parser()))
+	assert.Equal(context.Canceled, k.ReloadContext(ctx, "*file.File '"+mockJSON+"'"))
+	assert.Len(k.Layers(), 1)
+
+}
+
+// valProvider is a Provider that is a value with an interface field.
+type valProvider struct {
+	data interface{}
+}
+
+func (v valProvider) ReadBytes() ([]byte, error) {
+	return nil, errors.New("not supported")
+}
+
+func (v valProvider) Read() (map[string]interface{}, error) {
+	return map[string]interface{}{"data": v.data}, nil
+}
+
+func (v valProvider) Watch(cb func(event interface{}, err error)) error {
+	return nil
+}
+
+// wrapError is an error that wraps another error.
+type wrapError struct {
+	msg string
+	err error
+}
+
+func (e *wrapError) Error() string {
+	return e.msg + ": " + e.err.Error()
+}
+
+func (e *wrapError) Unwrap() error {
+	return e.err
+}
+
+// flakyProvider is a Provider that fails to read a number of times.
+type flakyProvider struct {
+	fails int
+	reads int
+
+	// err is the error of the failed reads, `unavailable` if it's nil.
+	err error
+}
+
+func (f *flakyProvider) ReadBytes() ([]byte, error) {
+	f.reads++
+	if f.reads <= f.fails {
+		if f.err != nil {
+			return nil, f.err
+		}
+		return nil, errors.New("unavailable")
+	}
+	return []byte(`{"name": "flaky"}`), nil
+}
+
+func (f *flakyProvider) Read() (map[string]interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (f *flakyProvider) Watch(cb func(event interface{}, err error)) error {
+	return nil
+}
+
+func TestLoadOptionalRetryFallback(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "koanf_optional")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+	var (
+		fa    = filepath.Join(dir, "a.json")
+		cache = filepath.Join(dir, "cache.json")
+	)
+	assert.Nil(ioutil.WriteFile(cache, []byte(`{"name": "cache"}`), 0600))
+
+	// Missing optional sources are loaded as empty layers.
+	k := koanf.New(delim)
+	err = k.Load(file.Provider(fa), json.Parser())
+	assert.IsType(&koanf.ProviderError{}, err)
+	assert.True(os.IsNotExist(err.(*koanf.ProviderError).Err))
+	assert.Nil(k.Load(file.Provider(fa), json.Parser(), koanf.WithName("a"), koanf.WithOptional()))
+	assert.Empty(k.Keys())
+	assert.Equal([]koanf.Layer{{Name: "a"}}, k.Layers())
+
+	assert.Nil(ioutil.WriteFile(fa, []byte(`{"name": "a"}`), 0600))
+	assert.Nil(k.Reload("a"))
+	assert.Equal("a", k.String("name"))
+
+	// Other errors are not ignored.
+	assert.Nil(ioutil.WriteFile(fa, []byte(`{"name": `), 0600))
+	assert.Error(k.Reload("a"))
+	assert.Equal("a", k.String("name"))
+
+	// Retries.
+	k = koanf.New(delim)
+	f := &flakyProvider{fails: 2}
+	assert.EqualError(k.Load(f, json.Parser(), koanf.WithRetry(2, time.Millisecond)),
+		"error reading *koanf_test.flakyProvider: unavailable")
+	assert.Equal(2, f.reads)
+
+	f = &flakyProvider{fails: 2}
+	assert.Nil(k.Load(f, json.Parser(), koanf.WithRetry(3, time.Millisecond)))
+	assert.Equal(3, f.reads)
+	assert.Equal("flaky", k.String("name"))
+
+	// Waiting for a retry stops when the context is done.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	f = &flakyProvider{fails: 5}
+	assert.Equal(context.DeadlineExceeded, k.LoadContext(ctx, f, json.Parser(), koanf.WithRetry(5, time.Second)))
+	assert.Equal(1, f.reads)
+
+	// Fallbacks.
+	k = koanf.New(delim)
+	f = &flakyProvider{fails: 5}
+	assert.Nil(k.Load(f, json.Parser(), koanf.WithName("remote"), koanf.WithRetry(2, time.Millisecond),
+		koanf.WithFallback(file.Provider(filepath.Join(dir, "missing.json")), json.Parser()),
+		koanf.WithFallback(file.Provider(cache), json.Parser())))
+	assert.Equal(2, f.reads)
+	assert.Equal("cache", k.String("name"))
+	assert.Equal("*file.File '"+cache+"'", k.Source("name"))
+	assert.Equal([]koanf.Layer{{Name: "remote"}}, k.Layers())
+
+	// The provider is tried again on reload.
+	f.fails = 0
+	assert.Nil(k.Reload("remote"))
+	assert.Equal("flaky", k.String("name"))
+	assert.Equal("*koanf_test.flakyProvider", k.Source("name"))
+
+	// Only missing sources are ignored.
+	f = &flakyProvider{fails: 1}
+	assert.EqualError(k.Load(f, json.Parser(), koanf.WithOptional(),
+		koanf.WithFallback(file.Provider(filepath.Join(dir, "missing.json")), json.Parser())),
+		fmt.Sprintf("error reading *file.File '%[1]s': open %[1]s: no such file or directory", filepath.Join(dir, "missing.json")))
+
+	// Wrapped errors of missing sources are ignored without retrying.
+	k = koanf.New(delim)
+	f = &flakyProvider{fails: 5, err: &wrapError{msg: "fetching config", err: os.ErrNotExist}}
+	assert.Nil(k.Load(f, json.Parser(), koanf.WithName("remote"), koanf.WithOptional(),
+		koanf.WithRetry(3, time.Millisecond)))
+	assert.Equal(1, f.reads)
+	assert.Equal([]koanf.Layer{{Name: "remote"}}, k.Layers())
+	assert.Empty(k.Keys())
+}
+
+func TestLoadErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "koanf_errors")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	k := koanf.New(delim)
+
+	// Provider errors.
+	fa := filepath.Join(dir, "missing.json")
+	err = k.Load(file.Provider(fa), json.Parser())
+	pe, ok := err.(*koanf.ProviderError)
+	assert.True(ok)
+	assert.Equal("*file.File", pe.Provider)
+	assert.Equal(fa, pe.Path)
+	assert.True(os.IsNotExist(pe.Err))
+
+	// Parse errors with the positions reported by the bundled parsers.
+	cases := []struct {
+		name   string
+		doc    string
+		parser koanf.Parser
+		line   int
+		column int
+	}{
+		{"a.json", "{\n \"a\": 1,\n \"b\" 2}", json.Parser(), 3, 6},
+		{"a.yml", "a: 1\nb: [\nc: d: e", yaml.Parser(), 2, 0},
+		{"a.toml", "a = 1\nb = = 2", toml.Parser(), 2, 5},
+		{"a.hcl", "a = 1\nb = = 2", hcl.Parser(true), 2, 5},
+		{"b.json", "[1]", json.Parser(), 1, 1},
+	}
+	for _, c := range cases {
+		f := filepath.Join(dir, c.name)
+		assert.Nil(ioutil.WriteFile(f, []byte(c.doc), 0600))
+
+		err := k.Load(file.Provider(f), c.parser)
+		pe, ok := err.(*koanf.ParseError)
+		if !assert.True(ok, c.name) {
+			continue
+		}
+		assert.Equal("*file.File", pe.Provider, c.name)
+		assert.Equal(f, pe.Path, c.name)
+		assert.Equal([]int{c.line, c.column}, []int{pe.Line, pe.Column}, c.name)
+
+		_, ok = pe.Err.(*poserr.Error)
+		assert.True(ok, c.name)
+	}
+
+	err = k.Load(rawbytes.Provider([]byte(`{"a": 1, "b" 2}`)), json.Parser())
+	assert.Equal("error parsing *rawbytes.RawBytes at line 1, column 14: invalid character '2' after object key", err.Error())
+	err = k.Load(rawbytes.Provider([]byte("a: 1\n  b: 2")), yaml.Parser())
+	assert.Equal("error parsing *rawbytes.RawBytes at line 2: yaml: line 2: mapping values are not allowed in this context", err.Error())
+
+	// Merge errors.
+	k = koanf.NewWithConf(koanf.Conf{Delim: delim, CaseInsensitive: true})
+	err = k.Load(rawbytes.Provider([]byte(`{"a": 1, "A": 2}`)), json.Parser())
+	me, ok := err.(*koanf.MergeError)
+	assert.True(ok)
+	assert.Equal("*rawbytes.RawBytes", me.Provider)
+	assert.Contains(me.Error(), "error merging *rawbytes.RawBytes: keys ")
+
+	// Context errors are returned as they are.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(context.Canceled, k.LoadContext(ctx, file.Provider(fa), json.Parser()))
+	assert.Empty(k.Keys())
+}
@@ -0,0 +1,508 @@
+package koanf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/knadh/koanf/maps"
+)
+
+// Layer describes a config map loaded into a Koanf instance.
+// See Layers().
+type Layer struct {
+	// Name is the name of the layer given with WithName(), or the name
+	// of its provider (see Source()), for instance,
+	// `*file.File '/etc/app.yaml'`, suffixed with a number if there are
+	// layers from multiple providers with the same name, for instance,
+	// `*env.Env:2`.
+	Name string
+
+	// Priority is the priority of the layer given with WithPriority().
+	Priority int
+}
+
+// layer is a config map loaded into a Koanf instance that
+// is merged with the other layers into the effective view.
+type layer struct {
+	Layer
+
+	// mp is the prepared config map of the layer. It is never
+	// modified and copies of it are merged.
+	mp map[string]interface{}
+
+	// src is the source of the keys in mp (see Source()), unless
+	// sources, the sources of individual keys, is set. See Merge().
+	src     string
+	sources map[string]string
+
+	// provider, parser and opts are used to Reload() the layer.
+	// Layers without a provider can't be reloaded.
+	provider Provider
+	parser   Parser
+	opts     []Option
+}
+
+// source is a Provider and the Parser to parse it with.
+type source struct {
+	p  Provider
+	pa Parser
+}
+
+// setOp is a value set with Set(), or a key path deleted with
+// Delete(), that is applied over the layers in the effective view.
+type setOp struct {
+	parts []string
+	val   interface{}
+	del   bool
+}
+
+// Layers returns the layers that are loaded, in the order of their
+// precedence, from the lowest to the highest.
+func (ko *Koanf) Layers() []Layer {
+	out := make([]Layer, 0, len(ko.layers))
+	for _, l := range ko.orderedLayers() {
+		out = append(out, l.Layer)
+	}
+	return out
+}
+
+// Unload removes the layer with the given name and recomputes the
+// effective config map from the remaining layers and values set with
+// Set().
+func (ko *Koanf) Unload(name string) error {
+	return ko.mutate(func() error {
+		i := ko.layerIndex(name)
+		if i < 0 {
+			return fmt.Errorf("unknown layer '%s'", name)
+		}
+
+		ko.layers = append(ko.layers[:i], ko.layers[i+1:]...)
+		return ko.recompute()
+	})
+}
+
+// Reload reads the provider of the layer with the given name again with
+// the Parser and Options it was loaded with, replaces the layer, and
+// recomputes the effective config map. Only layers loaded with Load() can
+// be reloaded.
+func (ko *Koanf) Reload(name string) error {
+	return ko.ReloadContext(context.Background(), name)
+}
+
+// ReloadContext is like Reload but stops reading the provider if the
+// given context is done before the read completes. See LoadContext().
+func (ko *Koanf) ReloadContext(ctx context.Context, name string) error {
+	return ko.mutate(func() error {
+		return ko.reload(ctx, name)
+	})
+}
+
+// reload reads the provider of the layer with the given name again.
+func (ko *Koanf) reload(ctx context.Context, name string) error {
+	i := ko.layerIndex(name)
+	if i < 0 {
+		return fmt.Errorf("unknown layer '%s'", name)
+	}
+
+	old := ko.layers[i]
+	if old.provider == nil {
+		return fmt.Errorf("layer '%s' cannot be reloaded", name)
+	}
+
+	var o loadOptions
+	for _, opt := range old.opts {
+		opt(&o)
+	}
+
+	mp, sp, err := o.read(ctx, old.provider, old.parser)
+	if err != nil {
+		return err
+	}
+
+	l := *old
+	l.mp = mp
+	l.src = providerName(sp)
+	if err := ko.addLayer(&l, i); err != nil {
+		return mergeError(sp, err)
+	}
+	return nil
+}
+
+// load reads the provider and loads its config map as a layer
+// named by the options, or by the provider.
+func (ko *Koanf) load(ctx context.Context, p Provider, pa Parser, opts []Option) error {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	mp, sp, err := o.read(ctx, p, pa)
+	if err != nil {
+		return err
+	}
+
+	l := &layer{
+		Layer:    Layer{Name: o.name, Priority: o.priority},
+		mp:       mp,
+		src:      providerName(sp),
+		provider: p,
+		parser:   pa,
+		opts:     opts,
+	}
+
+	// A named layer, or a layer from the same provider instance or
+	// an equal one, replaces the existing layer.
+	i := -1
+	if o.name != "" {
+		i = ko.layerIndex(o.name)
+	} else {
+		i = ko.providerIndex(p)
+		if i < 0 {
+			i = ko.equalProviderIndex(p)
+		}
+		if i >= 0 {
+			l.Name = ko.layers[i].Name
+		} else {
+			l.Name = ko.uniqueName(providerName(p))
+		}
+	}
+
+	if err := ko.addLayer(l, i); err != nil {
+		return mergeError(sp, err)
+	}
+	return nil
+}
+
+// addLayer prepares the config map of the layer l, applying its options,
+// and adds the layer, replacing the layer at the index i if it's >= 0.
+func (ko *Koanf) addLayer(l *layer, i int) error {
+	var o loadOptions
+	for _, opt := range l.opts {
+		opt(&o)
+	}
+
+	mp, err := ko.prepare(o.apply(l.mp, ko.conf.Delim), l.src, l.sources)
+	if err != nil {
+		return err
+	}
+	l.mp = mp
+
+	if i >= 0 {
+		ko.layers[i] = l
+		return ko.recompute()
+	}
+
+	// A layer that takes precedence over all existing layers is merged
+	// into the effective config map. Otherwise, or if the config is
+	// interpolated, it is recomputed.
+	top := len(ko.sets) == 0 && ko.interp == nil
+	for _, e := range ko.layers {
+		if e.Priority > l.Priority {
+			top = false
+			break
+		}
+	}
+
+	ko.layers = append(ko.layers, l)
+	if !top {
+		return ko.recompute()
+	}
+
+	ko.mergeMap(copyMap(l.mp))
+	ko.layerSources(l)
+	return nil
+}
+
+// recompute rebuilds the effective config map by merging the layers
+// in order, applying the values set with Set() and Delete(), and
+// interpolating it again if Interpolate() was used.
+func (ko *Koanf) recompute() error {
+	ko.confMap = make(map[string]interface{})
+	ko.sources = make(map[string]string)
+	ko.shared = false
+
+	for _, l := range ko.orderedLayers() {
+		maps.Merge(copyMap(l.mp), ko.confMap)
+		ko.layerSources(l)
+	}
+
+	// Values that no longer have a path to be set at are skipped.
+	for _, s := range ko.sets {
+		if s.del {
+			maps.Delete(ko.confMap, s.parts)
+			continue
+		}
+
+		v := copyValue(s.val)
+		if err := setPath(ko.confMap, s.parts, v, ko.conf.IndexSlices); err == nil {
+			ko.setSources(s.parts, v, "set")
+		}
+	}
+
+	ko.flatten()
+	if ko.interp != nil {
+		return ko.interpolate(*ko.interp)
+	}
+	return nil
+}
+
+// addSet records a value set with Set() or a deletion, discarding
+// the values set earlier at the same key path or under it.
+func (ko *Koanf) addSet(op setOp) {
+	out := ko.sets[:0]
+	for _, s := range ko.sets {
+		if !hasParts(s.parts, op.parts) {
+			out = append(out, s)
+		}
+	}
+	ko.sets = append(out, op)
+}
+
+// layerSources records the sources of the keys in the layer l.
+func (ko *Koanf) layerSources(l *layer) {
+	if l.sources == nil {
+		ko.setSources(nil, l.mp, l.src)
+		return
+	}
+	for k, src := range l.sources {
+		ko.sources[k] = src
+	}
+}
+
+// orderedLayers returns the layers sorted by priority.
+// Layers with the same priority retain the order they were loaded in.
+func (ko *Koanf) orderedLayers() []*layer {
+	out := make([]*layer, len(ko.layers))
+	copy(out, ko.layers)
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Priority < out[j].Priority
+	})
+	return out
+}
+
+// layerIndex returns the index of the layer with
+// the given name, or -1 if there's none.
+func (ko *Koanf) layerIndex(name string) int {
+	for i, l := range ko.layers {
+		if l.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// providerIndex returns the index of the layer loaded from the
+// given provider instance, or -1 if there's none. Only pointers are
+// compared as values of comparable types can still hold uncomparable
+// values in interface fields, which panic when compared.
+func (ko *Koanf) providerIndex(p Provider) int {
+	if reflect.ValueOf(p).Kind() != reflect.Ptr {
+		return -1
+	}
+	for i, l := range ko.layers {
+		if l.provider != nil && reflect.ValueOf(l.provider).Kind() == reflect.Ptr && l.provider == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// equalProviderIndex returns the index of the layer loaded from a
+// provider of the same type as p that reads from the same location
+// (see Locator), or that is deeply equal to p, or -1 if there's none.
+// For instance, a new file.Provider() for the same file replaces the
+// layer of the earlier one.
+func (ko *Koanf) equalProviderIndex(p Provider) int {
+	loc := location(p)
+	for i, l := range ko.layers {
+		if l.provider == nil || reflect.TypeOf(l.provider) != reflect.TypeOf(p) {
+			continue
+		}
+		if loc != "" && location(l.provider) == loc {
+			return i
+		}
+		if reflect.DeepEqual(l.provider, p) {
+			return i
+		}
+	}
+	return -1
+}
+
+// uniqueName returns the given name, suffixed with a number
+// if a layer already has it, for instance, `*env.Env:2`.
+func (ko *Koanf) uniqueName(name string) string {
+	if ko.layerIndex(name) < 0 {
+		return name
+	}
+
+	// Number from the count of the names given so far to
+	// not search through all the numbers taken.
+	for {
+		ko.names[name]++
+		s := name + ":" + strconv.Itoa(ko.names[name]+1)
+		if ko.layerIndex(s) < 0 {
+			return s
+		}
+	}
+}
+
+// read reads the config map from the provider p, and if a Parser is
+// given, parses it, retrying and falling back to other providers as per
+// the options. It returns the config map and the provider that it was
+// read from.
+func (o *loadOptions) read(ctx context.Context, p Provider, pa Parser) (map[string]interface{}, Provider, error) {
+	var (
+		err      error
+		notFound = true
+	)
+	for _, s := range append([]source{{p: p, pa: pa}}, o.fallbacks...) {
+		var mp map[string]interface{}
+		if mp, err = o.readRetry(ctx, s.p, s.pa); err == nil {
+			return mp, s.p, nil
+		}
+		if ctx.Err() != nil {
+			return nil, nil, err
+		}
+		notFound = notFound && isNotExist(err)
+	}
+
+	if o.optional && notFound {
+		return map[string]interface{}{}, p, nil
+	}
+	return nil, nil, err
+}
+
+// readRetry reads the config map from the provider, and if a Parser is
+// given, parses it, retrying failed reads as per the options. Errors are
+// returned as ProviderError or ParseError unless the context is done.
+func (o *loadOptions) readRetry(ctx context.Context, p Provider, pa Parser) (map[string]interface{}, error) {
+	wait := o.wait
+	for n := 1; ; n++ {
+		res := readProvider(ctx, p, pa)
+		if res.err == nil {
+			if pa == nil {
+				return res.mp, nil
+			}
+			mp, err := pa.Unmarshal(res.b)
+			if err != nil {
+				return nil, parseError(p, err)
+			}
+			return mp, nil
+		}
+		if ctx.Err() != nil && res.err == ctx.Err() {
+			return nil, res.err
+		}
+		if n >= o.retries || notExist(res.err) || ctx.Err() != nil {
+			return nil, providerError(p, res.err)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
+		wait *= 2
+	}
+}
+
+// readProvider reads the config map from the provider if no Parser
+// is given, or the raw bytes to parse if one is.
+func readProvider(ctx context.Context, p Provider, pa Parser) readResult {
+	if p == nil {
+		return readResult{err: errors.New("nil provider")}
+	}
+	if err := ctx.Err(); err != nil {
+		return readResult{err: err}
+	}
+
+	// No Parser is given. Call the Provider's Read() method to get
+	// the config map.
+	if pa == nil {
+		if cp, ok := p.(ContextProvider); ok {
+			mp, err := cp.ReadContext(ctx)
+			return readResult{mp: mp, err: err}
+		}
+		return readAsync(ctx, func() (r readResult) {
+			r.mp, r.err = p.Read()
+			return r
+		})
+	}
+
+	// There's a Parser. Get raw bytes from the Provider to parse.
+	if cp, ok := p.(ContextProvider); ok {
+		b, err := cp.ReadBytesContext(ctx)
+		return readResult{b: b, err: err}
+	}
+	return readAsync(ctx, func() (r readResult) {
+		r.b, r.err = p.ReadBytes()
+		return r
+	})
+}
+
+// readResult is the result of reading a provider.
+type readResult struct {
+	b   []byte
+	mp  map[string]interface{}
+	err error
+}
+
+// readAsync calls read, and if the context can be done, abandons
+// it when the context is done before it returns.
+func readAsync(ctx context.Context, read func() readResult) readResult {
+	if ctx.Done() == nil {
+		return read()
+	}
+
+	ch := make(chan readResult, 1)
+	go func() {
+		ch <- read()
+	}()
+
+	select {
+	case r := <-ch:
+		return r
+	case <-ctx.Done():
+		return readResult{err: ctx.Err()}
+	}
+}
+
+// hasParts returns true if the key path parts
+// are equal to, or under, the prefix parts.
+func hasParts(parts, prefix []string) bool {
+	if len(parts) < len(prefix) {
+		return false
+	}
+	return strings.Join(parts[:len(prefix)], "\x00") == strings.Join(prefix, "\x00")
+}
+
+// copyMap returns a deep copy of the config map mp.
+func copyMap(mp map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(mp))
+	for k, v := range mp {
+		out[k] = copyValue(v)
+	}
+	return out
+}
+
+// copyValue returns a deep copy of the maps and slices in v.
+func copyValue(v interface{}) interface{} {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		return copyMap(c)
+	case []interface{}:
+		out := make([]interface{}, len(c))
+		for i, item := range c {
+			out[i] = copyValue(item)
+		}
+		return out
+	}
+	return v
+}
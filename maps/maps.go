@@ -6,6 +6,7 @@ package maps
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -17,54 +18,86 @@ import (
 // Use IntfaceKeysToStrings() to convert if necessary.
 //
 // eg: `{ "parent": { "child": 123 }}` becomes `{ "parent.child": 123 }`
+// Delimiters within keys are escaped with a backslash (see JoinKey()), for
+// eg: `{ "hosts": { "example.com": 1 }}` becomes `{ "hosts.example\.com": 1 }`.
 // In addition, it keeps track of and returns a map of the delimited keypaths with
 // a slice of key parts, for eg: { "parent.child": ["parent", "child"] }. This
 // parts list is used to remember the key path's original structure to
 // unflatten later.
 func Flatten(m map[string]interface{}, keys []string, delim string) (map[string]interface{}, map[string][]string) {
+	out := make(map[string]interface{})
+	keyMap := make(map[string][]string)
+	flatten(m, keys, delim, false, out, keyMap)
+	return out, keyMap
+}
+
+// FlattenSlices is like Flatten, but in addition to maps, it also expands
+// slices into keys with their numeric indices as key parts.
+//
+// eg: `{ "parent": [{ "child": 123 }]}` becomes `{ "parent.0.child": 123 }`
+// Empty slices, like empty maps, are retained as values.
+func FlattenSlices(m map[string]interface{}, keys []string, delim string) (map[string]interface{}, map[string][]string) {
+	out := make(map[string]interface{})
+	keyMap := make(map[string][]string)
+	flatten(m, keys, delim, true, out, keyMap)
+	return out, keyMap
+}
+
+// flatten recursively flattens the given map or slice (if slices is true)
+// into the out and keyMap maps.
+func flatten(m interface{}, keys []string, delim string, slices bool, out map[string]interface{}, keyMap map[string][]string) {
+	// Collect the children of the map or slice with their key parts.
 	var (
-		out    = make(map[string]interface{})
-		keyMap = make(map[string][]string)
+		children  []interface{}
+		childKeys []string
 	)
-	for key, val := range m {
+	switch cur := m.(type) {
+	case map[string]interface{}:
+		for key, val := range cur {
+			childKeys = append(childKeys, key)
+			children = append(children, val)
+		}
+	case []interface{}:
+		for i, val := range cur {
+			childKeys = append(childKeys, strconv.Itoa(i))
+			children = append(children, val)
+		}
+	}
+
+	for i, val := range children {
 		// Copy the incoming key paths into a fresh list
 		// and append the current key in the iteration.
 		kp := make([]string, 0, len(keys)+1)
 		kp = append(kp, keys...)
-		kp = append(kp, key)
+		kp = append(kp, childKeys[i])
 
 		switch cur := val.(type) {
 		case map[string]interface{}:
-			// Empty map.
-			if len(cur) == 0 {
-				newKey := strings.Join(kp, delim)
-				out[newKey] = val
-				keyMap[newKey] = kp
-				continue
-			}
-
 			// It's a nested map. Flatten it recursively.
-			next, parts := Flatten(cur, kp, delim)
-
-			// Copy the resultant key parts and the value maps.
-			for k, p := range parts {
-				keyMap[k] = p
+			if len(cur) > 0 {
+				flatten(cur, kp, delim, slices, out, keyMap)
+				continue
 			}
-			for k, v := range next {
-				out[k] = v
+		case []interface{}:
+			// It's a slice. Flatten it recursively if slices
+			// are to be expanded.
+			if slices && len(cur) > 0 {
+				flatten(cur, kp, delim, slices, out, keyMap)
+				continue
 			}
-		default:
-			newKey := strings.Join(kp, delim)
-			out[newKey] = val
-			keyMap[newKey] = kp
 		}
+
+		// Empty maps, slices, and all other values.
+		newKey := JoinKey(kp, delim)
+		out[newKey] = val
+		keyMap[newKey] = kp
 	}
-	return out, keyMap
 }
 
 // Unflatten takes a flattened key:value map (non-nested with delimited keys)
 // and returns a nested map where the keys are split into hierarchies by the given
 // delimiter. For instance, `parent.child.key: 1` to `{parent: {child: {key: 1}}}`
+// Delimiters escaped with a backslash are not split (see SplitKey()).
 //
 // It's important to note that all nested maps should be
 // map[string]interface{} and not map[interface{}]interface{}.
@@ -75,7 +108,7 @@ func Unflatten(m map[string]interface{}, delim string) map[string]interface{} {
 	// Iterate through the flat conf map.
 	for k, v := range m {
 		var (
-			keys = strings.Split(k, delim)
+			keys = SplitKey(k, delim)
 			next = out
 		)
 
@@ -99,6 +132,147 @@ func Unflatten(m map[string]interface{}, delim string) map[string]interface{} {
 	return out
 }
 
+// UnflattenSlices is like Unflatten, but in addition, it collapses
+// maps whose keys are exactly the consecutive numeric indices 0...n-1
+// into slices. For instance, `parent.0.child: 1` to `{parent: [{child: 1}]}`.
+// It is the inverse of FlattenSlices.
+func UnflattenSlices(m map[string]interface{}, delim string) map[string]interface{} {
+	out := Unflatten(m, delim)
+	for k, v := range out {
+		out[k] = collapseSlices(v)
+	}
+	return out
+}
+
+// collapseSlices recursively converts maps with consecutive numeric
+// keys 0...n-1 into slices.
+func collapseSlices(v interface{}) interface{} {
+	mp, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+
+	for k, val := range mp {
+		mp[k] = collapseSlices(val)
+	}
+
+	// An empty map is not a slice.
+	if len(mp) == 0 {
+		return mp
+	}
+
+	out := make([]interface{}, len(mp))
+	for k, val := range mp {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 || i >= len(mp) || strconv.Itoa(i) != k {
+			return mp
+		}
+		out[i] = val
+	}
+	return out
+}
+
+// EscapeKey escapes the delimiter in a single key (a part of a key path)
+// with a backslash so that a key that contains the delimiter is not
+// confused with a nested key path. For instance, with the delimiter `.`,
+// the key `example.com` becomes `example\.com`. Backslashes are only
+// escaped, by doubling them, where they precede a delimiter or end the key.
+// Other backslashes, for instance, in `C:\tmp`, are left as they are.
+func EscapeKey(key, delim string) string {
+	if (delim == "" || !strings.Contains(key, delim)) && !strings.HasSuffix(key, `\`) {
+		return key
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(key); {
+		switch {
+		case key[i] == '\\':
+			n := backslashes(key[i:])
+			i += n
+			if i == len(key) || (delim != "" && strings.HasPrefix(key[i:], delim)) {
+				n *= 2
+			}
+			b.WriteString(strings.Repeat(`\`, n))
+		case delim != "" && strings.HasPrefix(key[i:], delim):
+			b.WriteString(`\` + delim)
+			i += len(delim)
+		default:
+			b.WriteByte(key[i])
+			i++
+		}
+	}
+	return b.String()
+}
+
+// JoinKey joins the parts of a key path with the delimiter, escaping
+// delimiters within the parts with EscapeKey().
+// eg: [hosts example.com] becomes `hosts.example\.com`.
+func JoinKey(parts []string, delim string) string {
+	esc := make([]string, len(parts))
+	for i, p := range parts {
+		esc[i] = EscapeKey(p, delim)
+	}
+	return strings.Join(esc, delim)
+}
+
+// SplitKey splits a key path by the delimiter into its parts, ignoring and
+// unescaping delimiters escaped with a backslash. It is the inverse of JoinKey().
+// Backslashes are only treated as escapes where they precede a delimiter
+// or end the path.
+// eg: `hosts.example\.com` becomes [hosts example.com].
+func SplitKey(path, delim string) []string {
+	if !strings.Contains(path, `\`) {
+		return strings.Split(path, delim)
+	}
+
+	var (
+		out []string
+		b   strings.Builder
+	)
+	for i := 0; i < len(path); {
+		switch {
+		case path[i] == '\\':
+			n := backslashes(path[i:])
+			i += n
+
+			atDelim := delim != "" && strings.HasPrefix(path[i:], delim)
+			if !atDelim && i < len(path) {
+				b.WriteString(strings.Repeat(`\`, n))
+				continue
+			}
+
+			// Pairs of backslashes are escaped backslashes, and an odd
+			// one escapes the delimiter. A lone one at the end is kept.
+			b.WriteString(strings.Repeat(`\`, n/2))
+			if n%2 == 1 {
+				if atDelim {
+					b.WriteString(delim)
+					i += len(delim)
+				} else {
+					b.WriteByte('\\')
+				}
+			}
+		case delim != "" && strings.HasPrefix(path[i:], delim):
+			out = append(out, b.String())
+			b.Reset()
+			i += len(delim)
+		default:
+			b.WriteByte(path[i])
+			i++
+		}
+	}
+	return append(out, b.String())
+}
+
+// backslashes returns the number of backslashes that s starts with.
+func backslashes(s string) int {
+	n := 0
+	for n < len(s) && s[n] == '\\' {
+		n++
+	}
+	return n
+}
+
 // Merge recursively merges map a into b (left to right), mutating
 // and expanding map b. Note that there's no copying involved, so
 // map b will retain references to map a.
@@ -139,22 +313,70 @@ func Merge(a, b map[string]interface{}) {
 // map[string]interface{} and not map[interface{}]interface{}.
 // Use IntfaceKeysToStrings() to convert if necessary.
 func Search(mp map[string]interface{}, path []string) interface{} {
-	next, ok := mp[path[0]]
-	if ok {
-		if len(path) == 1 {
-			return next
-		}
-		switch next.(type) {
+	return search(mp, path, false)
+}
+
+// SearchSlices is like Search but numeric key parts are treated as
+// indices when they address slices, for eg:, parent.0.key -> [parent 0 key].
+func SearchSlices(mp map[string]interface{}, path []string) interface{} {
+	return search(mp, path, true)
+}
+
+// search searches a map for a given path, and if slices is
+// true, numeric key parts that address slices as indices.
+func search(mp map[string]interface{}, path []string, slices bool) interface{} {
+	var next interface{} = mp
+	for _, k := range path {
+		switch cur := next.(type) {
 		case map[string]interface{}:
-			return Search(next.(map[string]interface{}), path[1:])
+			v, ok := cur[k]
+			if !ok {
+				return nil
+			}
+			next = v
+		case []interface{}:
+			if !slices {
+				return nil
+			}
+			i, err := strconv.Atoi(k)
+			if err != nil || i < 0 || i >= len(cur) {
+				return nil
+			}
+			next = cur[i]
 		default:
 			return nil
-		} //
-		// It's important to note that all nested maps should be
-		// map[string]interface{} and not map[interface{}]interface{}.
-		// Use IntfaceKeysToStrings() to convert if necessary.
+		}
+	}
+	return next
+}
+
+// Delete removes the entry at the given path from the map. The path is
+// the key map slice, for eg:, parent.child.key -> [parent child key].
+// Nested maps on the path that are left empty are deleted.
+//
+// It's important to note that all nested maps should be
+// map[string]interface{} and not map[interface{}]interface{}.
+// Use IntfaceKeysToStrings() to convert if necessary.
+func Delete(mp map[string]interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+
+	next, ok := mp[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		delete(mp, path[0])
+		return
+	}
+
+	if sub, ok := next.(map[string]interface{}); ok {
+		Delete(sub, path[1:])
+		if len(sub) == 0 {
+			delete(mp, path[0])
+		}
 	}
-	return nil
 }
 
 // Copy returns a copy of a conf map by doing a JSON marshal+unmarshal
@@ -43,29 +43,84 @@ const delim = "."
 func TestFlatten(t *testing.T) {
 	f, k := Flatten(testMap, nil, delim)
 	assert.Equal(t, map[string]interface{}{
-		"parent.child.key":          123,
-		"parent.child.key.with.dot": 456,
-		"top":                       789,
-		"empty":                     map[string]interface{}{},
+		"parent.child.key":            123,
+		`parent.child.key\.with\.dot`: 456,
+		"top":                         789,
+		"empty":                       map[string]interface{}{},
 	}, f)
 	assert.Equal(t, map[string][]string{
-		"parent.child.key":          {"parent", "child", "key"},
-		"parent.child.key.with.dot": {"parent", "child", "key.with.dot"},
-		"top":                       {"top"},
-		"empty":                     {"empty"},
+		"parent.child.key":            {"parent", "child", "key"},
+		`parent.child.key\.with\.dot`: {"parent", "child", "key.with.dot"},
+		"top":                         {"top"},
+		"empty":                       {"empty"},
 	}, k)
 }
 
 func TestUnflatten(t *testing.T) {
 	m, _ := Flatten(testMap, nil, delim)
 	um := Unflatten(m, delim)
-	assert.NotEqual(t, um, testMap)
+	assert.Equal(t, um, testMap)
 
 	m, _ = Flatten(testMap2, nil, delim)
 	um = Unflatten(m, delim)
 	assert.Equal(t, um, testMap2)
 }
 
+func TestKeyEscape(t *testing.T) {
+	assert.Equal(t, "key", EscapeKey("key", delim))
+	assert.Equal(t, `example\.com`, EscapeKey("example.com", delim))
+	assert.Equal(t, `a\b`, EscapeKey(`a\b`, delim))
+	assert.Equal(t, `C:\tmp`, EscapeKey(`C:\tmp`, delim))
+	assert.Equal(t, `a\\\.b\\`, EscapeKey(`a\.b\`, delim))
+	assert.Equal(t, `a\__b`, EscapeKey("a__b", "__"))
+
+	parts := []string{"hosts", "example.com", `c:\`, "ip"}
+	assert.Equal(t, `hosts.example\.com.c:\\.ip`, JoinKey(parts, delim))
+	assert.Equal(t, parts, SplitKey(JoinKey(parts, delim), delim))
+	assert.Equal(t, []string{"a", "b"}, SplitKey("a.b", delim))
+	assert.Equal(t, []string{`a\b`, "c"}, SplitKey(`a\b.c`, delim))
+	assert.Equal(t, []string{"paths", `C:\tmp`}, SplitKey(`paths.C:\tmp`, delim))
+	assert.Equal(t, []string{`a\\b`, `c\`}, SplitKey(`a\\b.c\`, delim))
+
+	for _, parts := range [][]string{
+		{`a\.b\`, "c"},
+		{`\\`, `\.`, `x\y`},
+		{"", `\`},
+	} {
+		assert.Equal(t, parts, SplitKey(JoinKey(parts, delim), delim))
+	}
+
+	// Multi-character delimiters.
+	parts = []string{"a__", "b"}
+	assert.Equal(t, parts, SplitKey(JoinKey(parts, "__"), "__"))
+}
+
+func TestFlattenSlices(t *testing.T) {
+	f, k := FlattenSlices(testMap2, nil, delim)
+	assert.Equal(t, map[string]interface{}{
+		"list.0.child.key": 123,
+		"list.1.child.key": 123,
+		"parent.child.key": 123,
+		"top":              789,
+		"empty":            map[string]interface{}{},
+	}, f)
+	assert.Equal(t, []string{"list", "1", "child", "key"}, k["list.1.child.key"])
+
+	// Empty slices are retained as values.
+	f, _ = FlattenSlices(map[string]interface{}{"list": []interface{}{}}, nil, delim)
+	assert.Equal(t, map[string]interface{}{"list": []interface{}{}}, f)
+}
+
+func TestUnflattenSlices(t *testing.T) {
+	m, _ := FlattenSlices(testMap2, nil, delim)
+	assert.Equal(t, testMap2, UnflattenSlices(m, delim))
+
+	// Non-consecutive indices remain maps.
+	assert.Equal(t, map[string]interface{}{
+		"list": map[string]interface{}{"0": 1, "2": 2},
+	}, UnflattenSlices(map[string]interface{}{"list.0": 1, "list.2": 2}, delim))
+}
+
 func TestIntfaceKeysToStrings(t *testing.T) {
 	m := map[string]interface{}{
 		"list": []interface{}{
@@ -150,6 +205,40 @@ func TestSearch(t *testing.T) {
 	assert.Equal(t, 789, Search(testMap, []string{"top"}))
 	assert.Equal(t, map[string]interface{}{}, Search(testMap, []string{"empty"}))
 	assert.Nil(t, Search(testMap, []string{"xxx", "xxx"}))
+
+	// Slice indices.
+	assert.Nil(t, Search(testMap2, []string{"list", "1", "child", "key"}))
+	assert.Equal(t, 123, SearchSlices(testMap2, []string{"list", "1", "child", "key"}))
+	assert.Nil(t, SearchSlices(testMap2, []string{"list", "2", "child", "key"}))
+	assert.Nil(t, SearchSlices(testMap2, []string{"list", "x"}))
+}
+
+func TestDelete(t *testing.T) {
+	mp := map[string]interface{}{
+		"parent": map[string]interface{}{
+			"child": map[string]interface{}{
+				"key": 123,
+			},
+			"other": 1,
+		},
+		"top": 789,
+	}
+
+	Delete(mp, []string{"xxx", "xxx"})
+	Delete(mp, []string{"top", "xxx"})
+	Delete(mp, nil)
+	assert.Equal(t, 789, mp["top"])
+
+	// Empty parents are deleted.
+	Delete(mp, []string{"parent", "child", "key"})
+	assert.Equal(t, map[string]interface{}{
+		"parent": map[string]interface{}{"other": 1},
+		"top":    789,
+	}, mp)
+
+	Delete(mp, []string{"parent", "other"})
+	Delete(mp, []string{"top"})
+	assert.Equal(t, map[string]interface{}{}, mp)
 }
 
 func TestCopy(t *testing.T) {
@@ -0,0 +1,191 @@
+package koanf
+
+import (
+	"strings"
+	"time"
+
+	"github.com/knadh/koanf/maps"
+)
+
+// Option is an option that changes how Load() merges the config map
+// of a provider. Include, exclude, filter and transform options are
+// applied in the order they are given to the flattened key paths as
+// they are in the provider's config map, before WithPrefix().
+type Option func(*loadOptions)
+
+// loadOptions holds the options that are applied to a
+// provider's config map before it is merged.
+type loadOptions struct {
+	// name and priority are the name and the priority of the layer.
+	name     string
+	priority int
+
+	// prefix is the key path that the config map is mounted at.
+	prefix string
+
+	// optional, retries, wait and fallbacks change how the provider is
+	// read. See read().
+	optional  bool
+	retries   int
+	wait      time.Duration
+	fallbacks []source
+
+	// steps are the filters and transforms applied, in order, to every
+	// flattened key path and value in the config map. A step that
+	// returns false drops the key.
+	steps []func(key string, val interface{}, delim string) (string, interface{}, bool)
+}
+
+// WithName names the layer that the provider's config map is loaded as
+// (see Layers()). Loading a layer with the name of an existing layer
+// replaces it. Without a name, a layer is named after its provider, and
+// loading from the same provider instance again replaces its layer.
+func WithName(name string) Option {
+	return func(o *loadOptions) {
+		o.name = name
+	}
+}
+
+// WithPriority sets the priority of the layer that the provider's config
+// map is loaded as. Layers with a higher priority take precedence over
+// layers with a lower priority irrespective of the order they are loaded
+// in. Layers with the same priority, which is 0 by default, take
+// precedence in the order they are loaded in.
+func WithPriority(p int) Option {
+	return func(o *loadOptions) {
+		o.priority = p
+	}
+}
+
+// WithPrefix mounts the provider's config map at the given key path
+// before it is merged. For instance, loading `{host: localhost}` with
+// WithPrefix("cache.redis") sets `cache.redis.host`.
+func WithPrefix(path string) Option {
+	return func(o *loadOptions) {
+		o.prefix = path
+	}
+}
+
+// WithOptional ignores the error if the provider's source doesn't exist,
+// for instance, a missing file, and loads an empty layer that can be
+// reloaded once the source exists (see Reload()). An error is treated as
+// such if os.IsNotExist() returns true for it or for an error it wraps
+// with an `Unwrap() error` method. With WithFallback(), the
+// error is only ignored if none of the providers' sources exist.
+func WithOptional() Option {
+	return func(o *loadOptions) {
+		o.optional = true
+	}
+}
+
+// WithRetry reads the provider up to the given number of attempts if
+// reading it fails, waiting for the given duration before the second
+// attempt and twice as long as the previous wait before every subsequent
+// one. Errors from sources that don't exist (see WithOptional()) are not
+// retried, and retrying stops when the context given to LoadContext()
+// is done. Parse errors are not retried.
+func WithRetry(attempts int, wait time.Duration) Option {
+	return func(o *loadOptions) {
+		o.retries = attempts
+		o.wait = wait
+	}
+}
+
+// WithFallback reads the given provider, and parses it with pa if it's not
+// nil, if reading or parsing the provider, and the fallbacks given before
+// this one, fails, for instance, to load a locally cached copy when a
+// remote source is unavailable. WithRetry() applies to every provider.
+// The layer keeps its name, and Source() returns the name of the provider
+// that the config was read from. If every provider fails, the error of
+// the last one is returned.
+func WithFallback(p Provider, pa Parser) Option {
+	return func(o *loadOptions) {
+		o.fallbacks = append(o.fallbacks, source{p: p, pa: pa})
+	}
+}
+
+// WithInclude keeps only the key paths in the provider's config map that
+// are, or are under, one of the given key paths, for instance,
+// WithInclude("app", "db") keeps `app.name` and `db.host`.
+func WithInclude(paths ...string) Option {
+	return func(o *loadOptions) {
+		o.steps = append(o.steps, func(k string, v interface{}, delim string) (string, interface{}, bool) {
+			return k, v, hasPathPrefix(k, paths, delim)
+		})
+	}
+}
+
+// WithExclude drops the key paths in the provider's config map that
+// are, or are under, one of the given key paths.
+func WithExclude(paths ...string) Option {
+	return func(o *loadOptions) {
+		o.steps = append(o.steps, func(k string, v interface{}, delim string) (string, interface{}, bool) {
+			return k, v, !hasPathPrefix(k, paths, delim)
+		})
+	}
+}
+
+// WithFilter keeps only the key paths in the provider's config map for
+// which the given callback, that takes the flattened key path and its
+// value, returns true.
+func WithFilter(cb func(key string, val interface{}) bool) Option {
+	return func(o *loadOptions) {
+		o.steps = append(o.steps, func(k string, v interface{}, delim string) (string, interface{}, bool) {
+			return k, v, cb(k, v)
+		})
+	}
+}
+
+// WithTransform runs every flattened key path and value in the provider's
+// config map through the given callback which returns the new key path and
+// value, for instance, to rename keys or to trim values. Returning an empty
+// key path drops the key.
+func WithTransform(cb func(key string, val interface{}) (string, interface{})) Option {
+	return func(o *loadOptions) {
+		o.steps = append(o.steps, func(k string, v interface{}, delim string) (string, interface{}, bool) {
+			k, v = cb(k, v)
+			return k, v, k != ""
+		})
+	}
+}
+
+// apply applies the options to the config map mp
+// and returns the resultant config map.
+func (o *loadOptions) apply(mp map[string]interface{}, delim string) map[string]interface{} {
+	if len(o.steps) > 0 {
+		flat, _ := maps.Flatten(mp, nil, delim)
+
+		out := make(map[string]interface{}, len(flat))
+		for k, v := range flat {
+			keep := true
+			for _, s := range o.steps {
+				if k, v, keep = s(k, v, delim); !keep {
+					break
+				}
+			}
+			if keep {
+				out[k] = v
+			}
+		}
+		mp = maps.Unflatten(out, delim)
+	}
+
+	if o.prefix != "" && len(mp) > 0 {
+		parts := maps.SplitKey(o.prefix, delim)
+		for i := len(parts) - 1; i >= 0; i-- {
+			mp = map[string]interface{}{parts[i]: mp}
+		}
+	}
+	return mp
+}
+
+// hasPathPrefix returns true if the key path is, or is
+// under, one of the given key paths.
+func hasPathPrefix(key string, paths []string, delim string) bool {
+	for _, p := range paths {
+		if key == p || strings.HasPrefix(key, p+delim) {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,8 @@ import (
 	"errors"
 
 	"github.com/hashicorp/hcl"
+	"github.com/hashicorp/hcl/hcl/parser"
+	"github.com/knadh/koanf/parsers/poserr"
 )
 
 // HCL implements a Hashicorp HCL parser.
@@ -23,6 +25,9 @@ func Parser(flattenSlices bool) *HCL {
 func (p *HCL) Unmarshal(b []byte) (map[string]interface{}, error) {
 	o, err := hcl.Parse(string(b))
 	if err != nil {
+		if e, ok := err.(*parser.PosError); ok {
+			return nil, &poserr.Error{Line: e.Pos.Line, Column: e.Pos.Column, Err: err}
+		}
 		return nil, err
 	}
 
@@ -2,7 +2,10 @@
 package json
 
 import (
+	"bytes"
 	"encoding/json"
+
+	"github.com/knadh/koanf/parsers/poserr"
 )
 
 // JSON implements a JSON parser.
@@ -17,7 +20,7 @@ func Parser() *JSON {
 func (p *JSON) Unmarshal(b []byte) (map[string]interface{}, error) {
 	var out map[string]interface{}
 	if err := json.Unmarshal(b, &out); err != nil {
-		return nil, err
+		return nil, posError(b, err)
 	}
 	return out, nil
 }
@@ -26,3 +29,28 @@ func (p *JSON) Unmarshal(b []byte) (map[string]interface{}, error) {
 func (p *JSON) Marshal(o map[string]interface{}) ([]byte, error) {
 	return json.Marshal(o)
 }
+
+// posError returns err with its position in b if it reports its offset.
+func posError(b []byte, err error) error {
+	var off int64
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		off = e.Offset
+	case *json.UnmarshalTypeError:
+		off = e.Offset
+	default:
+		return err
+	}
+	if off > int64(len(b)) {
+		off = int64(len(b))
+	}
+
+	// The offset is past the byte that caused the error.
+	b = b[:off]
+	nl := bytes.LastIndexByte(b, '\n')
+	return &poserr.Error{
+		Line:   bytes.Count(b, []byte("\n")) + 1,
+		Column: len(b) - nl - 1,
+		Err:    err,
+	}
+}
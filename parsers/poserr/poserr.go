@@ -0,0 +1,28 @@
+// Package poserr implements the error that the bundled parsers return
+// with the position of syntax errors in the document, which koanf
+// reports in a koanf.ParseError.
+package poserr
+
+// Error is a syntax or type error with its position in the document.
+type Error struct {
+	// Line and Column are the position of the error, starting at 1.
+	// Column is 0 if the parser only reports the line.
+	Line   int
+	Column int
+
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Position returns the line and the column of the error.
+func (e *Error) Position() (line, column int) {
+	return e.Line, e.Column
+}
+
+// Unwrap returns the error from the underlying parser.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
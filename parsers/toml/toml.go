@@ -3,10 +3,17 @@ package toml
 
 import (
 	"bytes"
+	"regexp"
+	"strconv"
 
+	"github.com/knadh/koanf/parsers/poserr"
 	"github.com/pelletier/go-toml"
 )
 
+// rePos matches the position in TOML syntax errors,
+// eg: `(2, 5): cannot have multiple equals for the same key`.
+var rePos = regexp.MustCompile(`^\((\d+), (\d+)\): `)
+
 // TOML implements a TOML parser.
 type TOML struct{}
 
@@ -19,6 +26,11 @@ func Parser() *TOML {
 func (p *TOML) Unmarshal(b []byte) (map[string]interface{}, error) {
 	r, err := toml.LoadReader(bytes.NewBuffer(b))
 	if err != nil {
+		if m := rePos.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			col, _ := strconv.Atoi(m[2])
+			return nil, &poserr.Error{Line: line, Column: col, Err: err}
+		}
 		return nil, err
 	}
 	return r.ToMap(), err
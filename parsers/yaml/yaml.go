@@ -2,9 +2,17 @@
 package yaml
 
 import (
+	"regexp"
+	"strconv"
+
+	"github.com/knadh/koanf/parsers/poserr"
 	"gopkg.in/yaml.v2"
 )
 
+// reLine matches the line number in YAML syntax errors,
+// eg: `yaml: line 2: mapping values are not allowed`.
+var reLine = regexp.MustCompile(`^yaml: line (\d+):`)
+
 // YAML implements a YAML parser.
 type YAML struct{}
 
@@ -17,6 +25,10 @@ func Parser() *YAML {
 func (p *YAML) Unmarshal(b []byte) (map[string]interface{}, error) {
 	var out map[string]interface{}
 	if err := yaml.Unmarshal(b, &out); err != nil {
+		if m := reLine.FindStringSubmatch(err.Error()); m != nil {
+			line, _ := strconv.Atoi(m[1])
+			return nil, &poserr.Error{Line: line, Err: err}
+		}
 		return nil, err
 	}
 	return out, nil
@@ -0,0 +1,40 @@
+// Package defaults implements a koanf.Provider that reads the default
+// values in the `default` tags of a struct's fields as a conf map.
+package defaults
+
+import (
+	"errors"
+
+	"github.com/knadh/koanf"
+)
+
+// Defaults implements a struct default values provider.
+type Defaults struct {
+	s   interface{}
+	tag string
+}
+
+// Provider returns a provider that takes a struct and a struct tag and
+// provides a nested conf map of the values in the `default` tags of the
+// struct's fields keyed by tag. For instance, a field tagged
+// `koanf:"timeout" default:"30s"` provides {"timeout": "30s"}. It is meant
+// to be loaded first so that the defaults show up in Keys() and can be
+// overridden by other providers.
+func Provider(s interface{}, tag string) *Defaults {
+	return &Defaults{s: s, tag: tag}
+}
+
+// ReadBytes is not supported by the defaults provider.
+func (d *Defaults) ReadBytes() ([]byte, error) {
+	return nil, errors.New("defaults provider does not support this method")
+}
+
+// Read reads the struct's default values and returns a nested config map.
+func (d *Defaults) Read() (map[string]interface{}, error) {
+	return koanf.StructDefaults(d.s, d.tag), nil
+}
+
+// Watch is not supported by the defaults provider.
+func (d *Defaults) Watch(cb func(event interface{}, err error)) error {
+	return errors.New("defaults provider does not support this method")
+}
@@ -4,9 +4,13 @@
 package file
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"time"
 
@@ -23,16 +27,61 @@ func Provider(path string) *File {
 	return &File{path: filepath.Clean(path)}
 }
 
+// Location returns the path of the file.
+func (f *File) Location() string {
+	return f.path
+}
+
 // ReadBytes reads the contents of a file on disk and returns the bytes.
 func (f *File) ReadBytes() ([]byte, error) {
 	return ioutil.ReadFile(f.path)
 }
 
+// ReadBytesContext is like ReadBytes but stops reading the file and
+// returns ctx.Err() if the context is done, for instance, when reading
+// from a slow network mount.
+func (f *File) ReadBytesContext(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	fl, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer fl.Close()
+
+	// Read in chunks, checking the context between them.
+	var (
+		out bytes.Buffer
+		buf = make([]byte, 32*1024)
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		n, err := fl.Read(buf)
+		out.Write(buf[:n])
+		if err == io.EOF {
+			return out.Bytes(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
 // Read is not supported by the file provider.
 func (f *File) Read() (map[string]interface{}, error) {
 	return nil, errors.New("file provider does not support this method")
 }
 
+// ReadContext is not supported by the file provider.
+func (f *File) ReadContext(ctx context.Context) (map[string]interface{}, error) {
+	return f.Read()
+}
+
 // Watch watches the file and triggers a callback when it changes. It is a
 // blocking function that internally spawns a goroutine to watch for changes.
 func (f *File) Watch(cb func(event interface{}, err error)) error {
@@ -3,9 +3,13 @@
 package s3
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"log"
+	"net/http"
+	"os"
+	"strings"
 
 	"github.com/rhnvrm/simples3"
 )
@@ -45,27 +49,66 @@ func Provider(cfg Config) *S3 {
 	return &S3{s3: s3, cfg: cfg}
 }
 
+// Location returns the bucket and the key of the object.
+func (r *S3) Location() string {
+	return "s3://" + r.cfg.Bucket + "/" + r.cfg.ObjectKey
+}
+
 // ReadBytes reads the contents of a file on s3 and returns the bytes.
 func (r *S3) ReadBytes() ([]byte, error) {
-	resp, err := r.s3.FileDownload(simples3.DownloadInput{
-		Bucket:    r.cfg.Bucket,
-		ObjectKey: r.cfg.ObjectKey,
-	})
+	return r.download(r.s3)
+}
 
-	if err != nil {
-		data, _ := ioutil.ReadAll(resp)
-		log.Println(string(data))
+// ReadBytesContext is like ReadBytes but cancels the download and
+// returns the context's error if the context is done.
+func (r *S3) ReadBytesContext(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
 
-	data, err := ioutil.ReadAll(resp)
+	// simples3 doesn't take a context. Download with a copy of the
+	// client whose requests are made with the context.
+	c := http.DefaultClient
+	if r.s3.Client != nil {
+		c = r.s3.Client
+	}
+	cl := *c
+	cl.Transport = ctxTransport{ctx: ctx, rt: c.Transport}
+
+	s := *r.s3
+	s.SetClient(&cl)
+	b, err := r.download(&s)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return b, err
+}
+
+// download downloads the object with the given client.
+func (r *S3) download(s *simples3.S3) ([]byte, error) {
+	resp, err := s.FileDownload(simples3.DownloadInput{
+		Bucket:    r.cfg.Bucket,
+		ObjectKey: r.cfg.ObjectKey,
+	})
+
 	if err != nil {
+		// The download fails without a response body when the
+		// request fails, for instance, when it's cancelled.
+		if resp != nil {
+			data, _ := ioutil.ReadAll(resp)
+			log.Println(string(data))
+		}
+
+		// simples3 only reports the status of failed downloads. Missing
+		// objects are reported as such for koanf.WithOptional().
+		if strings.HasPrefix(err.Error(), "status code: 404") {
+			return nil, &os.PathError{Op: "download", Path: r.Location(), Err: os.ErrNotExist}
+		}
 		return nil, err
 	}
+	defer resp.Close()
 
-	resp.Close()
-
-	return data, nil
+	return ioutil.ReadAll(resp)
 }
 
 // Read returns the raw bytes for parsing.
@@ -73,6 +116,26 @@ func (r *S3) Read() (map[string]interface{}, error) {
 	return nil, errors.New("buf provider does not support this method")
 }
 
+// ReadContext is not supported.
+func (r *S3) ReadContext(ctx context.Context) (map[string]interface{}, error) {
+	return r.Read()
+}
+
+// ctxTransport makes HTTP requests with a context.
+type ctxTransport struct {
+	ctx context.Context
+	rt  http.RoundTripper
+}
+
+// RoundTrip makes the request with the context.
+func (t ctxTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt := t.rt
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return rt.RoundTrip(req.WithContext(t.ctx))
+}
+
 // Watch is not supported.
 func (r *S3) Watch(cb func(event interface{}, err error)) error {
 	return errors.New("S3 provider does not support this method")
@@ -0,0 +1,153 @@
+package koanf
+
+import (
+	"strconv"
+
+	"github.com/knadh/koanf/maps"
+)
+
+// Redacted is the value that the values of sensitive key
+// paths are masked with. See MarkSensitive().
+const Redacted = "******"
+
+// MarkSensitive marks key paths or patterns (see KeysMatching()) as
+// sensitive, for instance, `*.password` or `**.token`. The values of
+// sensitive key paths, and of all the key paths under them, are masked
+// with Redacted in Sprint(), Print(), AllRedacted() and MarshalRedacted().
+// Key paths within slices have the indices of the items as keys, for
+// instance, `servers.*.password` masks the passwords of all the servers.
+// Getters and All() still return the real values. Instances returned by
+// Cut() and Copy() retain the patterns that apply to them.
+func (ko *Koanf) MarkSensitive(patterns ...string) {
+	for _, p := range patterns {
+		ko.sensitive = append(ko.sensitive, maps.SplitKey(ko.foldPath(p), ko.conf.Delim))
+	}
+}
+
+// IsSensitive returns true if the given key path, or any of
+// its parents, matches a pattern marked as sensitive.
+func (ko *Koanf) IsSensitive(path string) bool {
+	if len(ko.sensitive) == 0 || path == "" {
+		return false
+	}
+
+	parts := maps.SplitKey(ko.foldPath(path), ko.conf.Delim)
+	for i := 1; i <= len(parts); i++ {
+		for _, p := range ko.sensitive {
+			if matchParts(p, parts[:i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllRedacted is like All() but with the values of
+// sensitive key paths masked with Redacted.
+func (ko *Koanf) AllRedacted() map[string]interface{} {
+	out := ko.All()
+	for k, v := range out {
+		out[k] = ko.redactValue(k, v)
+	}
+	return out
+}
+
+// redactValue returns the value v of the flat key path k masked with
+// Redacted if the path is sensitive, or with the sensitive key paths
+// within it masked otherwise.
+func (ko *Koanf) redactValue(k string, v interface{}) interface{} {
+	if ko.IsSensitive(k) {
+		return Redacted
+	}
+	return ko.redact(ko.keyMap[k], v)
+}
+
+// redact returns a copy of the slice or map v at the key path parts with
+// the values of the sensitive key paths within it masked. Other values
+// are returned as they are.
+func (ko *Koanf) redact(parts []string, v interface{}) interface{} {
+	if len(ko.sensitive) == 0 {
+		return v
+	}
+
+	switch c := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(c))
+		for k, item := range c {
+			out[k] = ko.redactItem(parts, k, item)
+		}
+		return out
+
+	case []interface{}:
+		out := make([]interface{}, len(c))
+		for i, item := range c {
+			out[i] = ko.redactItem(parts, strconv.Itoa(i), item)
+		}
+		return out
+	}
+	return v
+}
+
+// redactItem returns the item with the key k in the slice or map at the
+// key path parts masked with Redacted if its key path is sensitive.
+func (ko *Koanf) redactItem(parts []string, k string, item interface{}) interface{} {
+	kp := make([]string, 0, len(parts)+1)
+	kp = append(kp, parts...)
+	kp = append(kp, k)
+
+	for _, p := range ko.sensitive {
+		if matchParts(p, kp) {
+			return Redacted
+		}
+	}
+	return ko.redact(kp, item)
+}
+
+// MarshalRedacted is like Marshal() but with the values of
+// sensitive key paths masked with Redacted.
+func (ko *Koanf) MarshalRedacted(p Parser) ([]byte, error) {
+	return p.Marshal(ko.AllRedacted())
+}
+
+// cutPatterns returns the sensitive patterns that apply to the keys
+// under the key path parts once the path is cut out of them.
+func cutPatterns(patterns [][]string, parts []string, delim string) [][]string {
+	var (
+		out  [][]string
+		seen = make(map[string]bool)
+	)
+	for _, p := range patterns {
+		for _, c := range cutPattern(p, parts) {
+			k := maps.JoinKey(c, delim)
+			if !seen[k] {
+				seen[k] = true
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+// cutPattern returns the remainders of the pattern after
+// matching it against the leading key path parts.
+func cutPattern(pattern, parts []string) [][]string {
+	// The pattern matches the path or a parent of it, which
+	// makes everything under the path sensitive.
+	if len(pattern) == 0 {
+		return [][]string{{"**"}}
+	}
+
+	if len(parts) == 0 {
+		return [][]string{pattern}
+	}
+
+	if pattern[0] == "**" {
+		// Consume zero parts, or one part and retain the **.
+		return append(cutPattern(pattern[1:], parts), cutPattern(pattern, parts[1:])...)
+	}
+
+	if !matchParts(pattern[:1], parts[:1]) {
+		return nil
+	}
+	return cutPattern(pattern[1:], parts[1:])
+}
@@ -0,0 +1,147 @@
+package koanf
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/knadh/koanf/maps"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// SchemaSource is the source (see Source()) of the values
+// applied from the `default` values in a JSON Schema.
+const SchemaSource = "schema"
+
+// SchemaConf represents configuration options used by
+// ValidateSchemaWithConf() to validate the conf map.
+type SchemaConf struct {
+	// Defaults applies the `default` values of the properties in the
+	// schema to the conf map before validating it. Defaults are loaded
+	// as a layer named `schema` with the lowest priority and only fill
+	// key paths that are absent (see Layers()).
+	Defaults bool
+}
+
+// ValidateSchema validates the conf map against the given JSON Schema
+// document (drafts 4, 6 and 7) and returns all violations as
+// ValidationErrors where Key is the key path of the offending value and
+// Rule describes the violated constraint. To customize, use
+// ValidateSchemaWithConf().
+func (ko *Koanf) ValidateSchema(schema []byte) error {
+	return ko.ValidateSchemaWithConf(schema, SchemaConf{})
+}
+
+// ValidateSchemaWithConf is like ValidateSchema but takes configuration
+// params in SchemaConf, for instance, to apply the schema's defaults.
+func (ko *Koanf) ValidateSchemaWithConf(schema []byte, c SchemaConf) error {
+	s, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schema))
+	if err != nil {
+		return fmt.Errorf("error loading schema: %v", err)
+	}
+
+	if c.Defaults {
+		d, err := SchemaDefaults(schema)
+		if err != nil {
+			return err
+		}
+		if err := ko.mutate(func() error {
+			return ko.mergeDefaults(d, SchemaSource)
+		}); err != nil {
+			return err
+		}
+	}
+
+	res, err := s.Validate(gojsonschema.NewGoLoader(ko.confMap))
+	if err != nil {
+		return fmt.Errorf("error validating schema: %v", err)
+	}
+	if res.Valid() {
+		return nil
+	}
+
+	out := make(ValidationErrors, 0, len(res.Errors()))
+	for _, e := range res.Errors() {
+		out = append(out, ko.schemaError(e))
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+// schemaError converts a JSON Schema validation error into a
+// ValidationError with the key path of the offending value.
+func (ko *Koanf) schemaError(e gojsonschema.ResultError) ValidationError {
+	// The context is the path of the value in the document delimited
+	// by a character that can't occur in keys, starting with `(root)`.
+	parts := strings.Split(e.Context().String("\x00"), "\x00")[1:]
+
+	// Errors for missing and unknown properties are reported
+	// on the object containing them.
+	switch e.Type() {
+	case "required", "additional_property_not_allowed":
+		if p, ok := e.Details()["property"].(string); ok {
+			parts = append(parts, p)
+		}
+	}
+
+	key := maps.JoinKey(parts, ko.conf.Delim)
+	if key == "" || !ko.Exists(key) {
+		return ValidationError{Key: key, Rule: e.Description()}
+	}
+	return ValidationError{
+		Key:    key,
+		Rule:   e.Description(),
+		Value:  ko.Get(key),
+		Source: ko.Source(key),
+	}
+}
+
+// SchemaDefaults returns a nested conf map of the `default` values of
+// the properties, and recursively, of the nested properties, in the given
+// JSON Schema document. References ($ref) are not followed.
+func SchemaDefaults(schema []byte) (map[string]interface{}, error) {
+	var s map[string]interface{}
+	if err := json.Unmarshal(schema, &s); err != nil {
+		return nil, fmt.Errorf("error loading schema: %v", err)
+	}
+	return schemaDefaults(s), nil
+}
+
+// schemaDefaults recursively collects the default values of the
+// properties in the schema s.
+func schemaDefaults(s map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+
+	props, _ := s["properties"].(map[string]interface{})
+	for k, v := range props {
+		p, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if d, ok := p["default"]; ok {
+			out[k] = d
+			continue
+		}
+		if sub := schemaDefaults(p); len(sub) > 0 {
+			out[k] = sub
+		}
+	}
+	return out
+}
+
+// mergeDefaults loads the given defaults as a layer with the lowest
+// priority named src so that they only fill the key paths that are
+// absent. Loading defaults again replaces the layer.
+func (ko *Koanf) mergeDefaults(d map[string]interface{}, src string) error {
+	l := &layer{
+		Layer: Layer{Name: src, Priority: math.MinInt32},
+		mp:    d,
+		src:   src,
+	}
+	return ko.addLayer(l, ko.layerIndex(src))
+}
@@ -0,0 +1,189 @@
+package koanf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errReadOnly is returned by methods that change the config
+// of read-only instances such as snapshots.
+var errReadOnly = errors.New("config is read-only")
+
+// state is the config of a Koanf instance at a point in time. Its maps
+// are shared with the instance until the instance changes them.
+type state struct {
+	confMap     map[string]interface{}
+	confMapFlat map[string]interface{}
+	keyMap      KeyMap
+	sources     map[string]string
+	layers      []*layer
+	sets        []setOp
+	interp      *InterpolateConf
+}
+
+// Snapshot returns a read-only Koanf instance with the current config
+// that is unaffected by later changes to the instance, for instance, to
+// hand a consistent view of the config to a request while it's reloaded.
+// Snapshots are cheap as they share the config with the instance until
+// the instance changes it. Methods that change the config of a snapshot,
+// such as Load() and Set(), return an error. It is safe to call
+// Snapshot() while another goroutine changes the config.
+func (ko *Koanf) Snapshot() *Koanf {
+	ko.mu.Lock()
+	defer ko.mu.Unlock()
+	return ko.snapshot()
+}
+
+// snapshot returns a read-only copy of the config. ko.mu must be held.
+func (ko *Koanf) snapshot() *Koanf {
+	n := NewWithConf(ko.conf)
+	n.setState(ko.state())
+	n.sensitive = append([][]string(nil), ko.sensitive...)
+	n.readOnly = true
+	return n
+}
+
+// Rollback restores the config to what it was before the last n changes,
+// for instance, to revert a reload that turned out to be bad. Changes are
+// calls to methods such as Load(), Reload(), Merge() and Set() that
+// succeed. Up to Conf.History changes are kept. Rolled back changes
+// can't be restored.
+func (ko *Koanf) Rollback(n int) error {
+	if ko.readOnly {
+		return errReadOnly
+	}
+
+	ko.mu.Lock()
+	if n < 1 || n > len(ko.history) {
+		ko.mu.Unlock()
+		return fmt.Errorf("cannot roll back %d changes, history has %d", n, len(ko.history))
+	}
+
+	i := len(ko.history) - n
+	ko.setState(ko.history[i])
+	ko.history = ko.history[:i]
+	ko.version++
+	ko.mu.Unlock()
+
+	ko.changed()
+	return nil
+}
+
+// mutate runs fn that changes the config, and if fn succeeds, records
+// the config before the change in the history and notifies bindings.
+// If the config is interpolated, and fn fails, for instance, because a
+// new layer has a reference that can't be resolved, the config is
+// restored. Deprecated key paths are reported and bindings are
+// notified after the change is complete so that they can call the
+// methods of the instance.
+func (ko *Koanf) mutate(fn func() error) error {
+	if ko.readOnly {
+		return errReadOnly
+	}
+
+	dep, err := ko.change(fn)
+	for _, d := range dep {
+		ko.conf.OnDeprecated(d.old, d.new, d.src)
+	}
+	if err != nil {
+		return err
+	}
+	ko.changed()
+	return nil
+}
+
+// change runs fn that changes the config, holding the lock, and records
+// the config before the change in the history if fn succeeds. It returns
+// the deprecated key paths found during the change.
+func (ko *Koanf) change(fn func() error) ([]deprecation, error) {
+	ko.mu.Lock()
+	defer ko.mu.Unlock()
+
+	defer func() { ko.deprecated = nil }()
+	var (
+		s    state
+		save = ko.conf.History > 0 || ko.interp != nil
+	)
+	if save {
+		s = ko.state()
+	}
+	if err := fn(); err != nil {
+		if save {
+			ko.setState(s)
+		}
+		return ko.deprecated, err
+	}
+
+	if ko.conf.History > 0 {
+		ko.history = append(ko.history, s)
+		if len(ko.history) > ko.conf.History {
+			ko.history = ko.history[len(ko.history)-ko.conf.History:]
+		}
+	}
+	ko.version++
+	return ko.deprecated, nil
+}
+
+// changed updates the bindings after a change to the config. They
+// are updated from a snapshot as other goroutines may change the
+// config meanwhile.
+func (ko *Koanf) changed() {
+	ko.mu.Lock()
+	if len(ko.bindings) == 0 {
+		ko.mu.Unlock()
+		return
+	}
+	var (
+		bs = append([]*Binding(nil), ko.bindings...)
+		s  = ko.snapshot()
+		v  = ko.version
+	)
+	ko.mu.Unlock()
+
+	for _, b := range bs {
+		b.update(s, v)
+	}
+}
+
+// state returns the current config and marks its maps as
+// shared so that they are copied before they are changed.
+func (ko *Koanf) state() state {
+	ko.shared = true
+	return state{
+		confMap:     ko.confMap,
+		confMapFlat: ko.confMapFlat,
+		keyMap:      ko.keyMap,
+		sources:     ko.sources,
+		layers:      append([]*layer{}, ko.layers...),
+		sets:        append([]setOp{}, ko.sets...),
+		interp:      ko.interp,
+	}
+}
+
+// setState replaces the current config with the shared state s.
+func (ko *Koanf) setState(s state) {
+	ko.confMap = s.confMap
+	ko.confMapFlat = s.confMapFlat
+	ko.keyMap = s.keyMap
+	ko.sources = s.sources
+	ko.layers = append([]*layer{}, s.layers...)
+	ko.sets = append([]setOp{}, s.sets...)
+	ko.interp = s.interp
+	ko.shared = true
+}
+
+// own copies the conf map and the sources if they are shared
+// with snapshots or the history before they are changed in place.
+func (ko *Koanf) own() {
+	if !ko.shared {
+		return
+	}
+
+	ko.confMap = copyMap(ko.confMap)
+	src := make(map[string]string, len(ko.sources))
+	for k, v := range ko.sources {
+		src[k] = v
+	}
+	ko.sources = src
+	ko.shared = false
+}
@@ -0,0 +1,402 @@
+// Package structdoc generates a JSON Schema and a reference of all the
+// key paths from a struct that config is unmarshalled into, so that the
+// struct's `koanf` tags remain the single source of truth.
+package structdoc
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/knadh/koanf"
+	"github.com/knadh/koanf/internal/tags"
+	"github.com/knadh/koanf/maps"
+)
+
+// DescriptionTag is the struct field tag that holds the description
+// of a field, for instance, `description:"Port to listen on"`.
+const DescriptionTag = "description"
+
+// Conf represents configuration options used to walk a struct.
+type Conf struct {
+	// Tag is the struct field tag that maps fields to keys, as in
+	// koanf.UnmarshalConf.Tag. Defaults to `koanf`.
+	Tag string
+
+	// Delim is the delimiter of the key paths in the reference.
+	// Defaults to `.`.
+	Delim string
+}
+
+// Field is a key path in the reference generated from a struct field.
+type Field struct {
+	// Key is the key path of the field. Elements of maps and slices
+	// are denoted by `*`, for instance, `servers.*.port`.
+	Key string
+
+	// Type is the JSON Schema type of the field, for instance, `integer`.
+	// It is empty for interface{} fields that take any type. It is
+	// `string` for durations, which the schema also allows as numbers.
+	Type string
+
+	// GoType is the Go type of the field, for instance, `time.Duration`.
+	GoType string
+
+	// Default is the value in the field's `default` tag (koanf.DefaultTag).
+	Default    string
+	HasDefault bool
+
+	// Description is the value in the field's `description` tag.
+	Description string
+
+	// Required is true if the field's `validate` tag (koanf.ValidateTag)
+	// has the `required` rule.
+	Required bool
+}
+
+// textUnmarshaler is the type of encoding.TextUnmarshaler. Fields of
+// types that implement it are decoded from strings, for instance, net.IP.
+var textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Fields walks the given struct, or pointer to a struct, and returns the
+// fields of all its key paths in order, with nested structs, maps and
+// slices of structs followed by the key paths within them. Fields of
+// recursive types are returned, but the key paths within them are not.
+func Fields(s interface{}, c Conf) []Field {
+	c = withDefaults(c)
+
+	t := reflect.TypeOf(s)
+	if t == nil {
+		return nil
+	}
+	return fields(t, nil, c, make(map[reflect.Type]bool))
+}
+
+// fields recursively collects the fields of the struct type t. walking
+// has the struct types on the path being walked, which are not walked
+// again.
+func fields(t reflect.Type, parts []string, c Conf, walking map[reflect.Type]bool) []Field {
+	t = tags.Deref(t)
+	if t.Kind() != reflect.Struct || isText(t) || walking[t] {
+		return nil
+	}
+	walking[t] = true
+	defer delete(walking, t)
+
+	var out []Field
+	for _, f := range structFields(t, c.Tag) {
+		kp := make([]string, 0, len(parts)+1)
+		kp = append(kp, parts...)
+		kp = append(kp, f.Key)
+
+		d, hasDef := f.Tag.Lookup(koanf.DefaultTag)
+		out = append(out, Field{
+			Key:         maps.JoinKey(kp, c.Delim),
+			Type:        jsonType(f.Type),
+			GoType:      f.Type.String(),
+			Default:     d,
+			HasDefault:  hasDef,
+			Description: f.Tag.Get(DescriptionTag),
+			Required:    isRequired(f.StructField),
+		})
+
+		// Key paths within the field.
+		ft := tags.Deref(f.Type)
+		switch ft.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Array:
+			if !isText(ft) {
+				out = append(out, fields(ft.Elem(), append(kp, "*"), c, walking)...)
+			}
+		default:
+			out = append(out, fields(ft, kp, c, walking)...)
+		}
+	}
+	return out
+}
+
+// JSONSchema walks the given struct, or pointer to a struct, and returns
+// a JSON Schema (draft 7) document that describes the conf map it is
+// unmarshalled from. Types, defaults, descriptions and required fields
+// are taken from the fields' types and tags. Recursive struct types are
+// described once under `definitions` and referenced with `$ref`.
+func JSONSchema(s interface{}, c Conf) ([]byte, error) {
+	c = withDefaults(c)
+
+	t := reflect.TypeOf(s)
+	if t == nil || tags.Deref(t).Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a struct, got %v", t)
+	}
+
+	w := &schemaWalker{
+		c:       c,
+		root:    tags.Deref(t),
+		walking: make(map[reflect.Type]bool),
+		refs:    make(map[reflect.Type]bool),
+		defs:    make(map[string]interface{}),
+	}
+	out := w.typeSchema(t)
+	out["$schema"] = "http://json-schema.org/draft-07/schema#"
+	if len(w.defs) > 0 {
+		out["definitions"] = w.defs
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// schemaWalker builds the JSON Schema of a struct type.
+type schemaWalker struct {
+	c    Conf
+	root reflect.Type
+
+	// walking has the struct types on the path being walked, and refs
+	// the ones that are referenced from within themselves. defs are the
+	// schemas of the referenced types other than the root.
+	walking map[reflect.Type]bool
+	refs    map[reflect.Type]bool
+	defs    map[string]interface{}
+}
+
+// typeSchema returns the JSON Schema of the type t.
+func (w *schemaWalker) typeSchema(t reflect.Type) map[string]interface{} {
+	t = tags.Deref(t)
+
+	// A struct type within itself is a reference.
+	if w.walking[t] {
+		w.refs[t] = true
+		return map[string]interface{}{"$ref": w.ref(t)}
+	}
+
+	out := make(map[string]interface{})
+	if typ := jsonType(t); typ != "" {
+		out["type"] = typ
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Duration(0)):
+		// Bare numbers are durations in koanf.Conf.DurationUnit.
+		out["type"] = []string{"string", "number"}
+
+	case t == reflect.TypeOf(time.Time{}):
+		out["format"] = "date-time"
+
+	case isText(t):
+
+	case t.Kind() == reflect.Struct:
+		w.walking[t] = true
+		defer delete(w.walking, t)
+
+		var (
+			props = make(map[string]interface{})
+			req   []string
+		)
+		for _, f := range structFields(t, w.c.Tag) {
+			p := w.typeSchema(f.Type)
+			if d, ok := f.Tag.Lookup(koanf.DefaultTag); ok {
+				p["default"] = defaultValue(d, f.Type)
+			}
+			if d := f.Tag.Get(DescriptionTag); d != "" {
+				p["description"] = d
+			}
+			props[f.Key] = p
+
+			if isRequired(f.StructField) {
+				req = append(req, f.Key)
+			}
+		}
+		out["properties"] = props
+		if len(req) > 0 {
+			out["required"] = req
+		}
+
+		if w.refs[t] && t != w.root {
+			w.defs[t.String()] = out
+			return map[string]interface{}{"$ref": w.ref(t)}
+		}
+
+	case t.Kind() == reflect.Map:
+		out["additionalProperties"] = w.typeSchema(t.Elem())
+
+	case t.Kind() == reflect.Slice, t.Kind() == reflect.Array:
+		out["items"] = w.typeSchema(t.Elem())
+	}
+	return out
+}
+
+// ref returns the reference to the schema of the struct type t.
+func (w *schemaWalker) ref(t reflect.Type) string {
+	if t == w.root {
+		return "#"
+	}
+	return "#/definitions/" + t.String()
+}
+
+// Markdown walks the given struct, or pointer to a struct, and returns a
+// Markdown table of all its key paths with their types, defaults and
+// descriptions.
+func Markdown(s interface{}, c Conf) string {
+	var b strings.Builder
+	b.WriteString("| Key | Type | Default | Description |\n")
+	b.WriteString("| --- | --- | --- | --- |\n")
+
+	for _, f := range Fields(s, c) {
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s |\n",
+			f.Key, f.GoType, mdDefault(f), mdEscape(describe(f)))
+	}
+	return b.String()
+}
+
+// Text walks the given struct, or pointer to a struct, and returns a plain
+// text reference of all its key paths with their types, defaults and
+// descriptions in aligned columns.
+func Text(s interface{}, c Conf) string {
+	var (
+		b strings.Builder
+		w = tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	)
+	fmt.Fprintln(w, "KEY\tTYPE\tDEFAULT\tDESCRIPTION")
+	for _, f := range Fields(s, c) {
+		d := ""
+		if f.HasDefault {
+			d = strconv.Quote(f.Default)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", f.Key, f.GoType, d, describe(f))
+	}
+	w.Flush()
+	return b.String()
+}
+
+// structFields returns the exported fields of the struct type t that map
+// to keys based on the given struct field tag, like koanf.Unmarshal().
+// Fields with the `remain` tag option are skipped.
+func structFields(t reflect.Type, tag string) []tags.Field {
+	var out []tags.Field
+	for _, f := range tags.Fields(t, tag) {
+		if f.Remain || f.PkgPath != "" {
+			continue
+		}
+		out = append(out, f)
+	}
+	return out
+}
+
+// jsonType returns the JSON Schema type of the Go type t.
+func jsonType(t reflect.Type) string {
+	t = tags.Deref(t)
+
+	// Durations are configured as strings such as `30s`.
+	if t == reflect.TypeOf(time.Duration(0)) || isText(t) {
+		return "string"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return ""
+}
+
+// defaultValue converts the value in a `default` tag to the JSON
+// type of the field of type t where possible. Slices are split by
+// commas like koanf.StructDefaults().
+func defaultValue(d string, t reflect.Type) interface{} {
+	return jsonValue(tags.Default(d, t), t)
+}
+
+// jsonValue converts the string v, or the strings in the slice v,
+// to the JSON type of the type t where possible.
+func jsonValue(v interface{}, t reflect.Type) interface{} {
+	t = tags.Deref(t)
+
+	if sl, ok := v.([]interface{}); ok {
+		out := make([]interface{}, len(sl))
+		for i, item := range sl {
+			out[i] = jsonValue(item, t.Elem())
+		}
+		return out
+	}
+
+	d, _ := v.(string)
+	switch jsonType(t) {
+	case "integer":
+		if n, err := strconv.ParseInt(d, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(d, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(d); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// isRequired returns true if the field's `validate` tag
+// has the `required` rule.
+func isRequired(f reflect.StructField) bool {
+	for _, r := range strings.Split(f.Tag.Get(koanf.ValidateTag), ",") {
+		if strings.TrimSpace(r) == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// isText returns true if the type t is decoded from strings
+// with encoding.TextUnmarshaler.
+func isText(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(textUnmarshaler)
+}
+
+// describe returns the description of a field with `(required)`
+// appended to required fields.
+func describe(f Field) string {
+	if !f.Required {
+		return f.Description
+	}
+	if f.Description == "" {
+		return "(required)"
+	}
+	return f.Description + " (required)"
+}
+
+// mdDefault returns the default value of a field as Markdown.
+func mdDefault(f Field) string {
+	if !f.HasDefault {
+		return ""
+	}
+	return "`" + mdEscape(f.Default) + "`"
+}
+
+// mdEscape escapes pipes that break Markdown table cells.
+func mdEscape(s string) string {
+	return strings.Replace(s, "|", `\|`, -1)
+}
+
+// withDefaults returns the Conf with defaults for empty fields.
+func withDefaults(c Conf) Conf {
+	if c.Tag == "" {
+		c.Tag = "koanf"
+	}
+	if c.Delim == "" {
+		c.Delim = "."
+	}
+	return c
+}
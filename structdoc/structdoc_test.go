@@ -0,0 +1,168 @@
+package structdoc_test
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/knadh/koanf"
+	kjson "github.com/knadh/koanf/parsers/json"
+	"github.com/knadh/koanf/providers/rawbytes"
+	"github.com/knadh/koanf/structdoc"
+	"github.com/stretchr/testify/assert"
+)
+
+type server struct {
+	Host    string        `koanf:"host" validate:"required" description:"Host to listen on"`
+	Port    int           `koanf:"port" default:"8080" description:"Port | number"`
+	Timeout time.Duration `koanf:"timeout" default:"30s"`
+}
+
+type base struct {
+	Name string `koanf:"name" validate:"required"`
+}
+
+type conf struct {
+	base    `koanf:",squash"`
+	Debug   bool              `koanf:"debug" default:"false"`
+	Tags    []string          `koanf:"tags" default:"a,b"`
+	Ratio   float64           `koanf:"ratio" default:"0.5"`
+	IP      net.IP            `koanf:"ip"`
+	Started time.Time         `koanf:"started"`
+	Server  *server           `koanf:"server"`
+	Backups []server          `koanf:"backups"`
+	Extra   map[string]string `koanf:"extra"`
+	Any     interface{}       `koanf:"any"`
+	Skip    string            `koanf:"-"`
+	Rest    map[string]string `koanf:",remain"`
+	private string
+}
+
+func TestFields(t *testing.T) {
+	assert := assert.New(t)
+
+	var keys []string
+	for _, f := range structdoc.Fields(&conf{}, structdoc.Conf{}) {
+		keys = append(keys, f.Key)
+	}
+	assert.Equal([]string{"name", "debug", "tags", "ratio", "ip", "started",
+		"server", "server.host", "server.port", "server.timeout",
+		"backups", "backups.*.host", "backups.*.port", "backups.*.timeout",
+		"extra", "any"}, keys)
+
+	f := structdoc.Fields(conf{}, structdoc.Conf{Delim: "/"})
+	assert.Equal(structdoc.Field{Key: "server/host", Type: "string", GoType: "string",
+		Description: "Host to listen on", Required: true}, f[7])
+	assert.Equal(structdoc.Field{Key: "server/timeout", Type: "string", GoType: "time.Duration",
+		Default: "30s", HasDefault: true}, f[9])
+	assert.Equal("", f[15].Type)
+
+	// Custom tag.
+	type tagged struct {
+		Name string `yaml:"app_name"`
+	}
+	assert.Equal("app_name", structdoc.Fields(tagged{}, structdoc.Conf{Tag: "yaml"})[0].Key)
+	assert.Nil(structdoc.Fields(nil, structdoc.Conf{}))
+}
+
+func TestJSONSchema(t *testing.T) {
+	assert := assert.New(t)
+
+	b, err := structdoc.JSONSchema(&conf{}, structdoc.Conf{})
+	assert.Nil(err)
+
+	var s map[string]interface{}
+	assert.Nil(json.Unmarshal(b, &s))
+	assert.Equal("object", s["type"])
+	assert.Equal([]interface{}{"name"}, s["required"])
+
+	props := s["properties"].(map[string]interface{})
+	assert.Equal(map[string]interface{}{"type": "array", "default": []interface{}{"a", "b"},
+		"items": map[string]interface{}{"type": "string"}}, props["tags"])
+	assert.Equal(map[string]interface{}{"type": "string", "format": "date-time"}, props["started"])
+	assert.Equal(map[string]interface{}{"type": "string"}, props["ip"])
+	assert.Equal(map[string]interface{}{}, props["any"])
+	assert.Equal(map[string]interface{}{"type": "object",
+		"additionalProperties": map[string]interface{}{"type": "string"}}, props["extra"])
+	assert.Equal(map[string]interface{}{"type": "integer", "default": float64(8080), "description": "Port | number"},
+		props["server"].(map[string]interface{})["properties"].(map[string]interface{})["port"])
+
+	// The schema validates the conf map and applies its defaults.
+	k := koanf.New(".")
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"name": "app", "server": {"host": "localhost"}}`)), kjson.Parser()))
+	assert.Nil(k.ValidateSchemaWithConf(b, koanf.SchemaConf{Defaults: true}))
+	assert.Equal(8080, k.Int("server.port"))
+	assert.Equal(time.Second*30, k.Duration("server.timeout"))
+	assert.Equal(map[string]interface{}{"type": []interface{}{"string", "number"}, "default": "30s"},
+		props["server"].(map[string]interface{})["properties"].(map[string]interface{})["timeout"])
+
+	// Durations can be numbers.
+	assert.Nil(k.Set("server.timeout", 30))
+	assert.Nil(k.ValidateSchema(b))
+
+	assert.Nil(k.Set("server", map[string]interface{}{"port": "x"}))
+	assert.Error(k.ValidateSchema(b))
+
+	_, err = structdoc.JSONSchema("xxxx", structdoc.Conf{})
+	assert.Error(err)
+}
+
+func TestReference(t *testing.T) {
+	assert := assert.New(t)
+
+	md := structdoc.Markdown(server{}, structdoc.Conf{})
+	assert.Equal("| Key | Type | Default | Description |\n"+
+		"| --- | --- | --- | --- |\n"+
+		"| `host` | `string` |  | Host to listen on (required) |\n"+
+		"| `port` | `int` | `8080` | Port \\| number |\n"+
+		"| `timeout` | `time.Duration` | `30s` |  |\n", md)
+
+	txt := structdoc.Text(server{}, structdoc.Conf{})
+	assert.Equal("KEY      TYPE           DEFAULT  DESCRIPTION\n"+
+		"host     string                  Host to listen on (required)\n"+
+		"port     int            \"8080\"   Port | number\n"+
+		"timeout  time.Duration  \"30s\"    \n", txt)
+}
+
+type node struct {
+	Name     string  `koanf:"name" default:"node"`
+	Next     *node   `koanf:"next"`
+	Children []child `koanf:"children"`
+}
+
+type child struct {
+	Parent *node   `koanf:"parent"`
+	Kids   []child `koanf:"kids"`
+}
+
+func TestRecursive(t *testing.T) {
+	assert := assert.New(t)
+
+	var keys []string
+	for _, f := range structdoc.Fields(node{}, structdoc.Conf{}) {
+		keys = append(keys, f.Key)
+	}
+	assert.Equal([]string{"name", "next", "children", "children.*.parent", "children.*.kids"}, keys)
+
+	b, err := structdoc.JSONSchema(node{}, structdoc.Conf{})
+	assert.Nil(err)
+
+	var s map[string]interface{}
+	assert.Nil(json.Unmarshal(b, &s))
+	props := s["properties"].(map[string]interface{})
+	assert.Equal(map[string]interface{}{"$ref": "#"}, props["next"])
+	assert.Equal(map[string]interface{}{"type": "array",
+		"items": map[string]interface{}{"$ref": "#/definitions/structdoc_test.child"}}, props["children"])
+
+	def := s["definitions"].(map[string]interface{})["structdoc_test.child"].(map[string]interface{})
+	assert.Equal(map[string]interface{}{"$ref": "#"}, def["properties"].(map[string]interface{})["parent"])
+
+	// The schema validates recursive conf maps.
+	k := koanf.New(".")
+	assert.Nil(k.Load(rawbytes.Provider([]byte(`{"name": "a", "next": {"name": "b"},
+		"children": [{"kids": [{"parent": {"name": "c"}}]}]}`)), kjson.Parser()))
+	assert.Nil(k.ValidateSchema(b))
+	assert.Nil(k.Set("children", []interface{}{map[string]interface{}{"kids": "x"}}))
+	assert.Error(k.ValidateSchema(b))
+}
@@ -0,0 +1,261 @@
+package koanf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/koanf/internal/tags"
+	"github.com/knadh/koanf/maps"
+)
+
+// DefaultTag is the struct field tag that holds the default value of
+// a field, for instance, `default:"30s"`.
+const DefaultTag = "default"
+
+// matchKey returns the key in mp that matches the given field key, first
+// exactly, and then case insensitively, like mapstructure.
+func matchKey(mp map[string]interface{}, key string) (string, bool) {
+	if _, ok := mp[key]; ok {
+		return key, true
+	}
+	for k := range mp {
+		if strings.EqualFold(k, key) {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// strictChecker walks a conf map alongside the type of the struct it is
+// unmarshalled into to find keys that don't map to any field, and fields
+// that don't have a key.
+type strictChecker struct {
+	tag   string
+	delim string
+
+	// flat indicates that the keys at the top level of the conf map
+	// are flat, delimited key paths (UnmarshalConf.FlatPaths).
+	flat bool
+
+	// walking counts the struct types on the path being checked
+	// to stop at recursive types.
+	walking map[reflect.Type]int
+
+	unused []string
+	unset  []string
+}
+
+// keyPath returns a fresh copy of the key path parts with
+// the given key appended.
+func (s *strictChecker) keyPath(parts []string, key string, top bool) []string {
+	kp := make([]string, 0, len(parts)+1)
+	kp = append(kp, parts...)
+
+	// Flat keys are already delimited key paths.
+	if top && s.flat {
+		return append(kp, maps.SplitKey(key, s.delim)...)
+	}
+	return append(kp, key)
+}
+
+// checkUnused records the key paths in v that don't map to any field in t.
+func (s *strictChecker) checkUnused(v interface{}, t reflect.Type, parts []string, top bool) {
+	t = tags.Deref(t)
+
+	switch t.Kind() {
+	case reflect.Struct:
+		mp, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		used := make(map[string]bool, len(mp))
+		for _, f := range tags.Fields(t, s.tag) {
+			// All remaining keys are collected by the field.
+			if f.Remain {
+				return
+			}
+
+			k, ok := matchKey(mp, f.Key)
+			if !ok {
+				continue
+			}
+			used[k] = true
+			s.checkUnused(mp[k], f.Type, s.keyPath(parts, k, top), false)
+		}
+
+		for k := range mp {
+			if !used[k] {
+				s.unused = append(s.unused, maps.JoinKey(s.keyPath(parts, k, top), s.delim))
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		sl, ok := v.([]interface{})
+		if !ok {
+			return
+		}
+		for i, item := range sl {
+			s.checkUnused(item, t.Elem(), s.keyPath(parts, strconv.Itoa(i), false), false)
+		}
+
+	case reflect.Map:
+		mp, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for k, item := range mp {
+			s.checkUnused(item, t.Elem(), s.keyPath(parts, k, top), false)
+		}
+	}
+}
+
+// checkUnset records the key paths of the exported fields in the struct
+// type t that don't have a key in v. It returns the number of
+// unset fields recorded.
+func (s *strictChecker) checkUnset(v interface{}, t reflect.Type, parts []string, top bool) int {
+	t = tags.Deref(t)
+	if t.Kind() != reflect.Struct {
+		return 0
+	}
+
+	s.walking[t]++
+	defer func() { s.walking[t]-- }()
+
+	var (
+		mp, _ = v.(map[string]interface{})
+		n     = 0
+	)
+	for _, f := range tags.Fields(t, s.tag) {
+		if f.Remain || f.PkgPath != "" {
+			continue
+		}
+
+		k, ok := matchKey(mp, f.Key)
+		if !ok {
+			k = f.Key
+		}
+		kp := s.keyPath(parts, k, top)
+
+		// Nested structs that are present are checked recursively.
+		// Absent nested structs report all their fields, or themselves
+		// if they have no exported fields (eg: time.Time) or are of a
+		// type that is being checked, that is, of a recursive type.
+		if ok {
+			if _, isMap := mp[k].(map[string]interface{}); isMap {
+				n += s.checkUnset(mp[k], f.Type, kp, false)
+			}
+			continue
+		}
+		if ft := tags.Deref(f.Type); ft.Kind() == reflect.Struct && s.walking[ft] == 0 {
+			if c := s.checkUnset(nil, f.Type, kp, false); c > 0 {
+				n += c
+				continue
+			}
+		}
+
+		s.unset = append(s.unset, maps.JoinKey(kp, s.delim))
+		n++
+	}
+	return n
+}
+
+// checkStrict checks the conf map v that is to be unmarshalled into o
+// based on the UnmarshalConf and returns an error listing the unknown
+// keys and unset fields, if any.
+func (ko *Koanf) checkStrict(path string, v interface{}, o interface{}, c UnmarshalConf) error {
+	var parts []string
+	if path != "" {
+		parts = maps.SplitKey(ko.foldPath(path), ko.conf.Delim)
+	}
+
+	s := &strictChecker{
+		tag:     c.DecoderConfig.TagName,
+		delim:   ko.conf.Delim,
+		flat:    c.FlatPaths,
+		walking: make(map[reflect.Type]int),
+	}
+
+	// The target may be set in a custom DecoderConfig instead.
+	if o == nil {
+		o = c.DecoderConfig.Result
+	}
+	t := reflect.TypeOf(o)
+	if t == nil {
+		return nil
+	}
+
+	var msg []string
+	if c.ErrorUnused {
+		s.checkUnused(v, t, parts, true)
+		if len(s.unused) > 0 {
+			sort.Strings(s.unused)
+			msg = append(msg, fmt.Sprintf("unknown keys: %s", strings.Join(s.unused, ", ")))
+		}
+	}
+	if c.ErrorUnset {
+		s.checkUnset(v, t, parts, true)
+		if len(s.unset) > 0 {
+			sort.Strings(s.unset)
+			msg = append(msg, fmt.Sprintf("unset fields: %s", strings.Join(s.unset, ", ")))
+		}
+	}
+
+	if len(msg) > 0 {
+		return fmt.Errorf("error unmarshalling: %s", strings.Join(msg, "; "))
+	}
+	return nil
+}
+
+// StructDefaults returns a nested conf map of the default values in the
+// `default` tags (DefaultTag) of the fields of the given struct, keyed
+// by the given struct field tag (`koanf` if left empty). For instance,
+// a field tagged `koanf:"timeout" default:"30s"` produces
+// {"timeout": "30s"}. Default values are strings. The default value of
+// a slice field is split by commas into a slice.
+func StructDefaults(s interface{}, tag string) map[string]interface{} {
+	if tag == "" {
+		tag = "koanf"
+	}
+
+	t := reflect.TypeOf(s)
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	return structDefaults(t, tag, make(map[reflect.Type]bool))
+}
+
+// structDefaults recursively collects the default values of the fields
+// of the struct type t. walking has the struct types on the path being
+// walked. Fields of those types, that is, of recursive types, are skipped.
+func structDefaults(t reflect.Type, tag string, walking map[reflect.Type]bool) map[string]interface{} {
+	out := make(map[string]interface{})
+
+	t = tags.Deref(t)
+	if t.Kind() != reflect.Struct || walking[t] {
+		return out
+	}
+	walking[t] = true
+	defer delete(walking, t)
+
+	for _, f := range tags.Fields(t, tag) {
+		if f.Remain || f.PkgPath != "" {
+			continue
+		}
+
+		if d, ok := f.Tag.Lookup(DefaultTag); ok {
+			out[f.Key] = tags.Default(d, f.Type)
+			continue
+		}
+
+		if tags.Deref(f.Type).Kind() == reflect.Struct {
+			if sub := structDefaults(f.Type, tag, walking); len(sub) > 0 {
+				out[f.Key] = sub
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,388 @@
+package koanf
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/knadh/koanf/internal/tags"
+	"github.com/knadh/koanf/maps"
+)
+
+// ValidateTag is the struct field tag that holds the validation rules
+// of a field, for instance, `validate:"required,min=1,max=65535"`.
+const ValidateTag = "validate"
+
+// ValidationError represents a single violation of a validation rule.
+type ValidationError struct {
+	// Key is the key path of the offending value.
+	Key string
+
+	// Rule is the rule that was violated, for instance, `max=10`.
+	Rule string
+
+	// Value is the offending value. It is nil for missing keys.
+	Value interface{}
+
+	// Source is the name of the provider that set the value (see Source()).
+	Source string
+}
+
+func (e ValidationError) Error() string {
+	if e.Value == nil {
+		return fmt.Sprintf("%s: failed rule '%s'", e.Key, e.Rule)
+	}
+
+	if e.Source == "" {
+		return fmt.Sprintf("%s: value '%v' failed rule '%s'", e.Key, e.Value, e.Rule)
+	}
+	return fmt.Sprintf("%s: value '%v' (from %s) failed rule '%s'", e.Key, e.Value, e.Source, e.Rule)
+}
+
+// ValidationErrors is a list of all the violations found in a validation.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	s := make([]string, len(e))
+	for i, v := range e {
+		s[i] = v.Error()
+	}
+	return "validation failed: " + strings.Join(s, "; ")
+}
+
+// Validate validates the values in the conf map against a set of rules
+// keyed by key paths and returns all violations as ValidationErrors. Key
+// paths can be patterns (see KeysMatching()). Rules are comma separated,
+// for instance, `required,min=1,max=65535`. The supported rules are:
+//
+// required: the key path must exist.
+// min=n, max=n: the value of numbers (including numeric strings), or the
+// length of other strings, slices and maps, must be >= n or <= n.
+// minlen=n, maxlen=n: the length of strings (including numeric strings,
+// for instance, PINs), slices and maps, or of numbers formatted as
+// strings, must be >= n or <= n.
+// oneof=a b c: the value must be one of the space separated values.
+// regex=expr: the value must match the regular expression. As the
+// expression may contain commas, it must be the last rule.
+//
+// Rules other than required are skipped for key paths that don't exist.
+func (ko *Koanf) Validate(rules map[string]string) error {
+	paths := make([]string, 0, len(rules))
+	for p := range rules {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	var out ValidationErrors
+	for _, p := range paths {
+		rs, err := parseRules(rules[p])
+		if err != nil {
+			return fmt.Errorf("invalid rules for %s: %v", p, err)
+		}
+
+		// Patterns apply the rules to all matching key paths.
+		keys := []string{p}
+		if strings.Contains(p, "*") {
+			keys = ko.KeysMatching(p)
+		}
+		for _, k := range keys {
+			out = append(out, ko.validateKey(k, rs)...)
+		}
+	}
+
+	if len(out) > 0 {
+		return out
+	}
+	return nil
+}
+
+// ValidateStruct validates the conf map at the given key path against the
+// rules in the `validate` tags (ValidateTag) of the fields of the struct o,
+// the target that the path would be unmarshalled into. The struct's fields
+// are mapped to key paths by the `koanf` tag. See Validate() for the rules.
+// min and max rules on string fields check the length of the strings,
+// that is, they are minlen and maxlen.
+func (ko *Koanf) ValidateStruct(path string, o interface{}) error {
+	return ko.ValidateStructWithConf(path, o, UnmarshalConf{})
+}
+
+// ValidateStructWithConf is like ValidateStruct but maps the struct's
+// fields to key paths like UnmarshalWithConf() with the given
+// UnmarshalConf, that is, by UnmarshalConf.Tag, and as flat key paths
+// with UnmarshalConf.FlatPaths.
+func (ko *Koanf) ValidateStructWithConf(path string, o interface{}, c UnmarshalConf) error {
+	t := reflect.TypeOf(o)
+	if t == nil {
+		return nil
+	}
+	if c.Tag == "" {
+		c.Tag = "koanf"
+	}
+
+	var parts []string
+	if path != "" {
+		parts = maps.SplitKey(ko.foldPath(path), ko.conf.Delim)
+	}
+
+	r := &ruleCollector{
+		tag:     c.Tag,
+		delim:   ko.conf.Delim,
+		flat:    c.FlatPaths,
+		rules:   make(map[string]structRule),
+		walking: make(map[reflect.Type]bool),
+	}
+	r.collect(t, parts, true)
+
+	// Struct rules are key paths and not patterns.
+	keys := make([]string, 0, len(r.rules))
+	for k := range r.rules {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var out ValidationErrors
+	for _, k := range keys {
+		sr := r.rules[k]
+		rs, err := parseRules(sr.rules)
+		if err != nil {
+			return fmt.Errorf("invalid rules for %s: %v", k, err)
+		}
+
+		// The sizes of strings are their lengths even if they're numeric.
+		if sr.text {
+			for i, rl := range rs {
+				if rl.name == "min" || rl.name == "max" {
+					rs[i].name += "len"
+				}
+			}
+		}
+		out = append(out, ko.validateKey(k, rs)...)
+	}
+
+	if len(out) > 0 {
+		return out
+	}
+	return nil
+}
+
+// structRule is the rules in the `validate` tag of a struct field.
+type structRule struct {
+	rules string
+
+	// text indicates that the field is a string.
+	text bool
+}
+
+// ruleCollector walks a struct type and collects the rules in the
+// `validate` tags of its fields keyed by their key paths.
+type ruleCollector struct {
+	tag   string
+	delim string
+
+	// flat indicates that the keys of the top level fields
+	// are flat, delimited key paths (UnmarshalConf.FlatPaths).
+	flat bool
+
+	rules map[string]structRule
+
+	// walking has the struct types on the path being walked. Fields of
+	// those types, that is, of recursive types, are not walked.
+	walking map[reflect.Type]bool
+}
+
+// collect recursively collects the rules of the fields of the struct type t.
+func (r *ruleCollector) collect(t reflect.Type, parts []string, top bool) {
+	t = tags.Deref(t)
+	if t.Kind() != reflect.Struct || r.walking[t] {
+		return
+	}
+	r.walking[t] = true
+	defer delete(r.walking, t)
+
+	for _, f := range tags.Fields(t, r.tag) {
+		if f.Remain || f.PkgPath != "" {
+			continue
+		}
+
+		kp := make([]string, 0, len(parts)+1)
+		kp = append(kp, parts...)
+		if top && r.flat {
+			kp = append(kp, maps.SplitKey(f.Key, r.delim)...)
+		} else {
+			kp = append(kp, f.Key)
+		}
+
+		if rs := f.Tag.Get(ValidateTag); rs != "" {
+			r.rules[maps.JoinKey(kp, r.delim)] = structRule{
+				rules: rs,
+				text:  tags.Deref(f.Type).Kind() == reflect.String,
+			}
+		}
+		r.collect(f.Type, kp, false)
+	}
+}
+
+// rule is a single parsed validation rule.
+type rule struct {
+	name string
+	arg  string
+
+	// Parsed arguments.
+	num float64
+	re  *regexp.Regexp
+}
+
+func (r rule) String() string {
+	if r.arg == "" {
+		return r.name
+	}
+	return r.name + "=" + r.arg
+}
+
+// parseRules parses a comma separated list of rules.
+func parseRules(s string) ([]rule, error) {
+	var out []rule
+	for s != "" {
+		var r string
+		if strings.HasPrefix(s, "regex=") {
+			// regex consumes the rest of the string.
+			r, s = s, ""
+		} else if i := strings.Index(s, ","); i >= 0 {
+			r, s = s[:i], s[i+1:]
+		} else {
+			r, s = s, ""
+		}
+
+		var (
+			kv = strings.SplitN(strings.TrimSpace(r), "=", 2)
+			rl = rule{name: kv[0]}
+		)
+		if len(kv) == 2 {
+			rl.arg = kv[1]
+		}
+
+		switch rl.name {
+		case "required":
+		case "min", "max", "minlen", "maxlen":
+			n, err := strconv.ParseFloat(rl.arg, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number in '%s'", r)
+			}
+			rl.num = n
+		case "oneof":
+		case "regex":
+			re, err := regexp.Compile(rl.arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regex in '%s': %v", r, err)
+			}
+			rl.re = re
+		case "":
+			continue
+		default:
+			return nil, fmt.Errorf("unknown rule '%s'", rl.name)
+		}
+		out = append(out, rl)
+	}
+	return out, nil
+}
+
+// validateKey validates the value at the given key path against the rules.
+func (ko *Koanf) validateKey(key string, rules []rule) []ValidationError {
+	var (
+		out    []ValidationError
+		exists = ko.Exists(key)
+		val    = ko.Get(key)
+		src    = ko.Source(key)
+	)
+	for _, r := range rules {
+		if r.name == "required" {
+			if !exists {
+				out = append(out, ValidationError{Key: key, Rule: r.String()})
+			}
+			continue
+		}
+
+		if !exists {
+			continue
+		}
+
+		if !r.check(val) {
+			out = append(out, ValidationError{Key: key, Rule: r.String(), Value: val, Source: src})
+		}
+	}
+	return out
+}
+
+// check returns true if the value v satisfies the rule.
+func (r rule) check(v interface{}) bool {
+	switch r.name {
+	case "min", "max":
+		n, ok := ruleSize(v)
+		if !ok {
+			return false
+		}
+		if r.name == "min" {
+			return n >= r.num
+		}
+		return n <= r.num
+
+	case "minlen", "maxlen":
+		n := ruleLen(v)
+		if r.name == "minlen" {
+			return n >= r.num
+		}
+		return n <= r.num
+
+	case "oneof":
+		s := fmt.Sprintf("%v", v)
+		for _, o := range strings.Fields(r.arg) {
+			if s == o {
+				return true
+			}
+		}
+		return false
+
+	case "regex":
+		return r.re.MatchString(fmt.Sprintf("%v", v))
+	}
+	return true
+}
+
+// ruleSize returns the number that min and max rules compare: the value
+// of numbers and numeric strings, and the length of strings, slices and maps.
+func ruleSize(v interface{}) (float64, bool) {
+	switch c := v.(type) {
+	case string:
+		if f, err := strconv.ParseFloat(c, 64); err == nil {
+			return f, true
+		}
+		return float64(len(c)), true
+	case []interface{}:
+		return float64(len(c)), true
+	case map[string]interface{}:
+		return float64(len(c)), true
+	case bool:
+		return 0, false
+	}
+
+	f, err := toFloat64(v)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// ruleLen returns the number that minlen and maxlen rules compare: the
+// length of strings, slices and maps, and of other values as strings.
+func ruleLen(v interface{}) float64 {
+	switch c := v.(type) {
+	case []interface{}:
+		return float64(len(c))
+	case map[string]interface{}:
+		return float64(len(c))
+	}
+	return float64(len(fmt.Sprintf("%v", v)))
+}